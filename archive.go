@@ -0,0 +1,369 @@
+package chew
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+	"github.com/mmatongo/chew/v1/internal/sniff"
+)
+
+/*
+ArchiveOptions limits how much of a ZIP, TAR or gzip archive (including .tar.gz) is extracted. The
+entries of an archive are processed like any other content, picking the processor by their name and
+leading bytes, and their chunks have a Source made of the source of the archive and the path of the
+entry, such as bundle.zip!/docs/report.pdf. Entries no processor handles are skipped, entries that fail
+to process are skipped too and reported in an error wrapping ErrPartialContent that comes along with the
+chunks of the others. Archives going over one of the limits fail as a whole with ErrArchiveLimit.
+
+Fields:
+  - MaxDepth: How many archives deep the archives found inside an archive are opened, defaults to 3, negative means they're skipped (e.g., 1)
+  - MaxEntries: Maximum number of entries processed, counting those of nested archives, defaults to 10000 (e.g., 500)
+  - MaxTotalSize: Maximum number of bytes decompressed, counting those of nested archives, defaults to 1 GiB (e.g., 200 << 20)
+  - MaxRatio: Maximum number of bytes decompressed from an archive for every byte of it, defaults to 100 (e.g., 20)
+
+Usage:
+
+	c := chew.New(chew.Config{
+	    Archives: chew.ArchiveOptions{
+	        MaxDepth:     1,
+	        MaxTotalSize: 200 << 20,
+	    },
+	})
+
+	chunks, err := c.Process(ctx, []string{"file:///uploads/bundle.tar.gz"})
+*/
+type ArchiveOptions = common.ArchiveOptions
+
+const (
+	defaultArchiveDepth     = 3
+	defaultArchiveEntries   = 10000
+	defaultArchiveTotalSize = 1 << 30
+	defaultArchiveRatio     = 100
+)
+
+func archiveOptions(config Config) ArchiveOptions {
+	opts := config.Archives
+	// 0 is the zero value of an option that isn't set, not opening nested archives takes a negative depth
+	if opts.MaxDepth == 0 {
+		opts.MaxDepth = defaultArchiveDepth
+	}
+	if opts.MaxEntries == 0 {
+		opts.MaxEntries = defaultArchiveEntries
+	}
+	if opts.MaxTotalSize == 0 {
+		opts.MaxTotalSize = defaultArchiveTotalSize
+	}
+	if opts.MaxRatio == 0 {
+		opts.MaxRatio = defaultArchiveRatio
+	}
+	return opts
+}
+
+// registerArchiveProcessor registers p for ZIP, TAR and gzip archives.
+func registerArchiveProcessor(r *Registry, p Processor) {
+	for _, contentType := range []string{
+		contentTypeZip, "application/x-zip-compressed",
+		contentTypeTar,
+		contentTypeGzip, "application/x-gzip",
+	} {
+		r.RegisterContentType(contentType, p, 0)
+	}
+	for _, ext := range []string{".zip", ".tar", ".gz", ".tgz"} {
+		r.RegisterExtension(ext, p, 0)
+	}
+	for _, contentType := range []string{contentTypeZip, contentTypeTar, contentTypeGzip} {
+		r.RegisterSniffer(sniffs(contentType), p, 0)
+	}
+}
+
+/*
+archiveProcessor processes the entries of an archive with the processors registered on c, so archives
+can hold any format c handles, archives included.
+*/
+type archiveProcessor struct {
+	c *Chew
+}
+
+func (p archiveProcessor) Process(r io.Reader, source string) ([]Chunk, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &archiveWalker{c: p.c, opts: archiveOptions(p.c.config)}
+	w.remaining = w.opts.MaxTotalSize
+	chunks, err := w.walk(content, archiveName(source), source, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(w.failed) > 0 {
+		return chunks, fmt.Errorf("%w: %w", ErrPartialContent, errors.Join(w.failed...))
+	}
+	return chunks, nil
+}
+
+// archiveWalker walks an archive and those nested in it, keeping track of the limits and of the entries that failed.
+type archiveWalker struct {
+	c         *Chew
+	opts      ArchiveOptions
+	remaining int64
+	entries   int
+	failed    []error
+}
+
+/*
+skip records the failure of an entry so the walk can go on with the next one, unless it's down to a
+limit being exceeded, which is returned to stop the walk.
+*/
+func (w *archiveWalker) skip(err error) error {
+	if errors.Is(err, ErrArchiveLimit) {
+		return err
+	}
+	w.failed = append(w.failed, err)
+	return nil
+}
+
+// walk processes the entries of the archive content, nested depth archives deep.
+func (w *archiveWalker) walk(content []byte, name, source string, depth int) ([]Chunk, error) {
+	if depth > max(w.opts.MaxDepth, 0) {
+		return nil, fmt.Errorf("%s: %w: nested more than %d archives deep", source, ErrArchiveLimit, w.opts.MaxDepth)
+	}
+
+	budget := int64(math.MaxInt64)
+	if b := float64(len(content)) * w.opts.MaxRatio; b < math.MaxInt64 {
+		budget = int64(b)
+	}
+
+	head := content[:min(len(content), sniffLen)]
+	switch {
+	case sniff.IsZip(head):
+		return w.walkZip(content, source, depth, &budget)
+	case sniff.IsTar(head):
+		return w.walkTar(bytes.NewReader(content), source, depth)
+	case sniff.IsGzip(head):
+		return w.walkGzip(content, name, source, depth, &budget)
+	}
+
+	return nil, fmt.Errorf("%s: %w: not a ZIP, TAR or gzip archive", source, ErrUnsupportedContentType)
+}
+
+func (w *archiveWalker) walkZip(content []byte, source string, depth int, budget *int64) ([]Chunk, error) {
+	zr, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	var chunks []Chunk
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() || isArchiveJunk(f.Name) {
+			continue
+		}
+
+		entryChunks, err := w.zipEntry(f, source, depth, budget)
+		if err != nil {
+			if err := w.skip(err); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		chunks = append(chunks, entryChunks...)
+	}
+
+	return chunks, nil
+}
+
+func (w *archiveWalker) zipEntry(f *zip.File, source string, depth int, budget *int64) ([]Chunk, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", entrySource(source, f.Name), err)
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(w.limit(rc, budget, source))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", entrySource(source, f.Name), err)
+	}
+
+	return w.entry(data, f.Name, entrySource(source, f.Name), depth)
+}
+
+/*
+walkTar processes the entries of the TAR archive read from r. A TAR archive isn't compressed, so reading
+it isn't limited here but by whatever r was decompressed from.
+*/
+func (w *archiveWalker) walkTar(r io.Reader, source string, depth int) ([]Chunk, error) {
+	tr := tar.NewReader(r)
+
+	var chunks []Chunk
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// there's no telling where the next entry starts, what was read so far is kept
+			if err := w.skip(fmt.Errorf("%s: %w", source, err)); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if hdr.Typeflag != tar.TypeReg || isArchiveJunk(hdr.Name) {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err == nil {
+			var entryChunks []Chunk
+			if entryChunks, err = w.entry(data, hdr.Name, entrySource(source, hdr.Name), depth); err == nil {
+				chunks = append(chunks, entryChunks...)
+				continue
+			}
+		} else {
+			err = fmt.Errorf("%s: %w", entrySource(source, hdr.Name), err)
+		}
+		if err := w.skip(err); err != nil {
+			return nil, err
+		}
+	}
+
+	return chunks, nil
+}
+
+/*
+walkGzip processes the content of a gzip stream. A .tar.gz is walked as the TAR archive it holds, any
+other content is processed as the single file it is, keeping the source of the stream.
+*/
+func (w *archiveWalker) walkGzip(content []byte, name, source string, depth int, budget *int64) ([]Chunk, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	defer zr.Close()
+
+	br := bufio.NewReader(w.limit(zr, budget, source))
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if sniff.IsTar(head) {
+		chunks, err := w.walkTar(br, source, depth)
+		if err != nil {
+			return nil, err
+		}
+		// the reads going over the limits may be buffered past the end of the TAR archive
+		if _, err := io.Copy(io.Discard, br); err != nil {
+			return nil, err
+		}
+		return chunks, nil
+	}
+
+	data, err := io.ReadAll(br)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	inner := zr.Name
+	if inner == "" {
+		inner = strings.TrimSuffix(name, path.Ext(name))
+	}
+	return w.entry(data, inner, source, depth)
+}
+
+/*
+entry processes a file found in an archive with the processor picked for it, or walks it if it's an
+archive itself.
+*/
+func (w *archiveWalker) entry(content []byte, name, source string, depth int) ([]Chunk, error) {
+	w.entries++
+	if w.entries > w.opts.MaxEntries {
+		return nil, fmt.Errorf("%s: %w: more than %d entries", source, ErrArchiveLimit, w.opts.MaxEntries)
+	}
+
+	contentType := mediaType(mime.TypeByExtension(path.Ext(name)))
+	proc, contentType, r, err := w.c.resolve(bytes.NewReader(content), contentType, name)
+	if errors.Is(err, ErrUnsupportedContentType) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	if _, ok := proc.(archiveProcessor); ok {
+		if w.opts.MaxDepth < 0 {
+			return nil, nil
+		}
+		return w.walk(content, name, source, depth+1)
+	}
+
+	chunks, err := proc.Process(r, source)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	for i := range chunks {
+		if chunks[i].Metadata.ContentType == "" {
+			chunks[i].Metadata.ContentType = mediaType(contentType)
+		}
+	}
+
+	return chunks, nil
+}
+
+// limit returns a reader failing with ErrArchiveLimit once more than budget or MaxTotalSize bytes are read from r.
+func (w *archiveWalker) limit(r io.Reader, budget *int64, source string) io.Reader {
+	return &limitedReader{r: r, w: w, budget: budget, source: source}
+}
+
+type limitedReader struct {
+	r      io.Reader
+	w      *archiveWalker
+	budget *int64
+	source string
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.w.remaining -= int64(n)
+	*l.budget -= int64(n)
+
+	switch {
+	case l.w.remaining < 0:
+		return n, fmt.Errorf("%s: %w: more than %d bytes decompressed", l.source, ErrArchiveLimit, l.w.opts.MaxTotalSize)
+	case *l.budget < 0:
+		return n, fmt.Errorf("%s: %w: compression ratio above %g", l.source, ErrArchiveLimit, l.w.opts.MaxRatio)
+	}
+	return n, err
+}
+
+// entrySource returns the source of the entry name of the archive found at source, e.g. bundle.zip!/docs/report.pdf.
+func entrySource(source, name string) string {
+	return source + "!" + path.Clean("/"+name)
+}
+
+/*
+isArchiveJunk reports whether name is metadata macOS adds to the archives it creates, AppleDouble files
+named after the files they belong to that would otherwise be processed as if they were those files.
+*/
+func isArchiveJunk(name string) bool {
+	return strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(path.Base(name), "._")
+}
+
+// archiveName returns the file name of the archive found at source, which may be a URL.
+func archiveName(source string) string {
+	if u, err := url.Parse(source); err == nil && u.Path != "" {
+		return path.Base(u.Path)
+	}
+	return path.Base(source)
+}
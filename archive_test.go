@@ -0,0 +1,207 @@
+package chew
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type archiveEntry struct {
+	name, content string
+}
+
+func makeZip(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeTarGz(t *testing.T, entries ...archiveEntry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(zw)
+	for _, e := range entries {
+		if err := tw.WriteHeader(&tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessBytes_Archives(t *testing.T) {
+	inner := makeZip(t, archiveEntry{name: "notes.md", content: "# Inner notes"})
+
+	tests := []struct {
+		name        string
+		content     []byte
+		hint        ContentHint
+		archives    ArchiveOptions
+		wantSources []string
+		wantErr     error
+	}{
+		{
+			name: "zip",
+			content: makeZip(t,
+				archiveEntry{name: "notes.md", content: "# Notes"},
+				archiveEntry{name: "data/people.csv", content: "name,age\nAda,36"},
+				archiveEntry{name: "data/", content: ""},
+				archiveEntry{name: "__MACOSX/data/._people.csv", content: "junk"},
+				archiveEntry{name: "tool.bin", content: "\x00\x01\x02"},
+			),
+			hint:        ContentHint{Filename: "bundle.zip"},
+			wantSources: []string{"bundle.zip!/data/people.csv", "bundle.zip!/notes.md"},
+		},
+		{
+			name:        "tar.gz",
+			content:     makeTarGz(t, archiveEntry{name: "./docs/config.json", content: `{"key": "value"}`}),
+			hint:        ContentHint{Source: "https://example.com/bundle.tar.gz"},
+			wantSources: []string{"https://example.com/bundle.tar.gz!/docs/config.json"},
+		},
+		{
+			name:        "gzip",
+			content:     gzipped(t, "# Compressed"),
+			hint:        ContentHint{Filename: "notes.md.gz"},
+			wantSources: []string{"notes.md.gz"},
+		},
+		{
+			name:        "nested",
+			content:     makeZip(t, archiveEntry{name: "inner.zip", content: string(inner)}),
+			hint:        ContentHint{MIME: "application/zip", Source: "outer.zip"},
+			wantSources: []string{"outer.zip!/inner.zip!/notes.md"},
+		},
+		{
+			name:        "nested archives off",
+			content:     makeZip(t, archiveEntry{name: "notes.md", content: "# Notes"}, archiveEntry{name: "inner.zip", content: string(inner)}),
+			hint:        ContentHint{Filename: "outer.zip"},
+			archives:    ArchiveOptions{MaxDepth: -1},
+			wantSources: []string{"outer.zip!/notes.md"},
+		},
+		{
+			name: "entry that fails",
+			content: makeZip(t,
+				archiveEntry{name: "notes.md", content: "# Notes"},
+				archiveEntry{name: "broken.pdf", content: "%PDF-1.4 garbage"},
+			),
+			hint:        ContentHint{Filename: "bundle.zip"},
+			wantSources: []string{"bundle.zip!/notes.md"},
+			wantErr:     ErrPartialContent,
+		},
+		{
+			name:     "too deep",
+			content:  makeZip(t, archiveEntry{name: "middle.zip", content: string(makeZip(t, archiveEntry{name: "inner.zip", content: string(inner)}))}),
+			hint:     ContentHint{Filename: "outer.zip"},
+			archives: ArchiveOptions{MaxDepth: 1},
+			wantErr:  ErrArchiveLimit,
+		},
+		{
+			name:     "too many entries",
+			content:  makeZip(t, archiveEntry{name: "a.md", content: "a"}, archiveEntry{name: "b.md", content: "b"}),
+			hint:     ContentHint{Filename: "bundle.zip"},
+			archives: ArchiveOptions{MaxEntries: 1},
+			wantErr:  ErrArchiveLimit,
+		},
+		{
+			name:     "too large",
+			content:  makeTarGz(t, archiveEntry{name: "notes.md", content: "# Far more than sixteen bytes"}),
+			hint:     ContentHint{Filename: "bundle.tgz"},
+			archives: ArchiveOptions{MaxTotalSize: 16},
+			wantErr:  ErrArchiveLimit,
+		},
+		{
+			name:    "compression ratio",
+			content: makeZip(t, archiveEntry{name: "bomb.md", content: strings.Repeat("a", 1<<20)}),
+			hint:    ContentHint{Filename: "bundle.zip"},
+			wantErr: ErrArchiveLimit,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{Archives: tt.archives})
+
+			chunks, err := c.ProcessBytes(context.Background(), tt.content, tt.hint)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ProcessBytes() error = %v, want %v", err, tt.wantErr)
+			}
+
+			seen := make(map[string]bool)
+			var sources []string
+			for _, chunk := range chunks {
+				if !seen[chunk.Source] {
+					seen[chunk.Source] = true
+					sources = append(sources, chunk.Source)
+				}
+				if chunk.Metadata.ContentType == "" || chunk.Metadata.FetchedAt.IsZero() {
+					t.Errorf("Metadata = %+v, want ContentType and FetchedAt filled in", chunk.Metadata)
+				}
+			}
+			sort.Strings(sources)
+
+			if !reflect.DeepEqual(sources, tt.wantSources) {
+				t.Errorf("sources = %v, want %v", sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestProcessBytes_ZipBasedDocument(t *testing.T) {
+	epub, err := os.ReadFile("testdata/files/test.epub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a document served as a plain ZIP archive is still processed as the document it is
+	chunks, err := New(Config{}).ProcessBytes(context.Background(), epub, ContentHint{MIME: "application/zip", Source: "book"})
+	if err != nil {
+		t.Fatalf("ProcessBytes() error = %v", err)
+	}
+	if len(chunks) == 0 || chunks[0].Metadata.ContentType != contentTypeEPUB {
+		t.Errorf("ProcessBytes() = %+v, want EPUB chunks", chunks)
+	}
+}
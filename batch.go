@@ -0,0 +1,109 @@
+package chew
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+/*
+SourceReport describes how processing a single source went.
+
+Fields:
+  - Err: The error that caused the source to fail, nil if it was processed successfully
+  - Attempts: Number of times the source was fetched, including retries
+  - StatusCode: The HTTP status code of the last response, 0 for files or if no response was received
+  - Duration: Total time spent on the source, including waiting on rate limits and crawl delays
+  - Depth: How many links away from a seed the source was found by Crawl, 0 for seeds and everything else
+*/
+type SourceReport struct {
+	Err        error
+	Attempts   int
+	StatusCode int
+	Duration   time.Duration
+	Depth      int
+}
+
+/*
+BatchResult holds the outcome of ProcessBatch. Chunks holds the chunks of every source that
+was processed successfully, or partly with an error wrapping ErrPartialContent in its report,
+grouped by the URL as it was passed in, or by the file:// URL of each file a directory or glob
+pattern expanded to. Reports holds a report for every source, successful or not.
+*/
+type BatchResult struct {
+	Chunks  map[string][]Chunk
+	Reports map[string]*SourceReport
+}
+
+// Failed returns the reports of the sources that could not be processed, keyed by URL.
+func (b *BatchResult) Failed() map[string]*SourceReport {
+	failed := make(map[string]*SourceReport)
+	for url, report := range b.Reports {
+		if report.Err != nil {
+			failed[url] = report
+		}
+	}
+	return failed
+}
+
+// Err returns an error summarising the failed sources, or nil if every source succeeded.
+func (b *BatchResult) Err() error {
+	failed := b.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d sources failed", len(failed), len(b.Reports))
+}
+
+/*
+ProcessBatch takes a list of URLs and processes all of them, unlike Process a failing URL doesn't
+stop the others from being processed. The returned BatchResult holds the chunks of the successful
+URLs along with a report for each URL so failed ones can be retried later.
+
+The error returned is only non-nil if the context was cancelled before every URL was processed, in
+which case the unprocessed URLs are reported with the context's error.
+
+This function is safe for concurrent use.
+
+Usage:
+
+	result, err := c.ProcessBatch(ctx, urls)
+	if err != nil {
+		log.Printf("Batch interrupted: %v", err)
+	}
+
+	for url, report := range result.Failed() {
+		log.Printf("%s failed after %d attempts (status %d): %v", url, report.Attempts, report.StatusCode, report.Err)
+	}
+*/
+func (c *Chew) ProcessBatch(ctx context.Context, urls []string) (*BatchResult, error) {
+	result := &BatchResult{
+		Chunks:  make(map[string][]common.Chunk),
+		Reports: make(map[string]*SourceReport, len(urls)),
+	}
+
+	for sr := range c.run(ctx, urls) {
+		result.Reports[sr.url] = &SourceReport{
+			Err:        sr.err,
+			Attempts:   sr.info.attempts,
+			StatusCode: sr.info.statusCode,
+			Duration:   sr.duration,
+		}
+		if sr.err == nil || len(sr.chunks) > 0 {
+			result.Chunks[sr.url] = sr.chunks
+		}
+	}
+
+	err := ctx.Err()
+	if err != nil {
+		for _, url := range urls {
+			if _, ok := result.Reports[url]; !ok {
+				result.Reports[url] = &SourceReport{Err: err}
+			}
+		}
+	}
+
+	return result, err
+}
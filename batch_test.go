@@ -0,0 +1,124 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+func TestProcessBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("A plain text file."))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("not really a png"))
+		}
+	}))
+	defer server.Close()
+
+	chew := New(Config{
+		IgnoreRobotsTxt: true,
+		RetryLimit:      0,
+		RateLimit:       time.Millisecond,
+		RateBurst:       10,
+	})
+
+	urls := []string{server.URL + "/text", server.URL + "/image", "file:///non-existent.md"}
+
+	result, err := chew.ProcessBatch(context.Background(), urls)
+	if err != nil {
+		t.Fatalf("ProcessBatch() error = %v", err)
+	}
+
+	wantChunks := map[string][]common.Chunk{
+		server.URL + "/text": {{Content: "A plain text file.", Source: server.URL + "/text", Metadata: common.Metadata{ContentType: "text/plain"}}},
+	}
+	for url, chunks := range result.Chunks {
+		result.Chunks[url] = stripMetadata(t, chunks)
+	}
+	if !reflect.DeepEqual(result.Chunks, wantChunks) {
+		t.Errorf("ProcessBatch() chunks = %v, want %v", result.Chunks, wantChunks)
+	}
+
+	if len(result.Reports) != len(urls) {
+		t.Fatalf("ProcessBatch() returned %d reports, want %d", len(result.Reports), len(urls))
+	}
+
+	ok := result.Reports[server.URL+"/text"]
+	if ok.Err != nil || ok.StatusCode != http.StatusOK || ok.Attempts < 1 {
+		t.Errorf("ProcessBatch() success report = %+v", ok)
+	}
+
+	unsupported := result.Reports[server.URL+"/image"]
+	if unsupported.Err == nil || unsupported.StatusCode != http.StatusOK {
+		t.Errorf("ProcessBatch() unsupported report = %+v", unsupported)
+	}
+
+	missing := result.Reports["file:///non-existent.md"]
+	if missing.Err == nil || missing.StatusCode != 0 {
+		t.Errorf("ProcessBatch() missing file report = %+v", missing)
+	}
+
+	if got := len(result.Failed()); got != 2 {
+		t.Errorf("Failed() returned %d reports, want 2", got)
+	}
+	if result.Err() == nil {
+		t.Error("Err() = nil, want an error")
+	}
+}
+
+func TestProcessBatch_PartialContent(t *testing.T) {
+	bundle := makeZip(t,
+		archiveEntry{name: "notes.md", content: "# Notes"},
+		archiveEntry{name: "broken.pdf", content: "%PDF-1.4 garbage"},
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Write(bundle)
+	}))
+	defer server.Close()
+
+	chew := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 1})
+
+	url := server.URL + "/bundle.zip"
+	result, err := chew.ProcessBatch(context.Background(), []string{url})
+	if err != nil {
+		t.Fatalf("ProcessBatch() error = %v", err)
+	}
+
+	if got := len(result.Chunks[url]); got == 0 {
+		t.Error("ProcessBatch() kept no chunks of the entries that were processed")
+	}
+	if report := result.Reports[url]; !errors.Is(report.Err, ErrPartialContent) {
+		t.Errorf("ProcessBatch() report error = %v, want %v", report.Err, ErrPartialContent)
+	}
+}
+
+func TestProcessBatch_ContextCancelled(t *testing.T) {
+	chew := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	urls := []string{"https://example.com/a", "https://example.com/b"}
+	result, err := chew.ProcessBatch(ctx, urls)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("ProcessBatch() error = %v, want %v", err, context.Canceled)
+	}
+
+	for _, url := range urls {
+		report, ok := result.Reports[url]
+		if !ok || report.Err == nil {
+			t.Errorf("ProcessBatch() report for %s = %+v, want an error", url, report)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package chew
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mmatongo/chew/v1/internal/cache"
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+/*
+Cache stores the responses fetched by chew, and optionally the chunks they were processed into, so
+runs over the same sources don't download and process everything again, see Config.Cache. Get
+returns ErrCacheMiss for keys that aren't cached.
+
+Failing to read from or write to the cache never fails a source, it's fetched and processed as if
+there was no cache.
+*/
+type Cache = common.Cache
+
+// ErrCacheMiss is returned by Cache.Get for keys that aren't cached.
+var ErrCacheMiss = common.ErrCacheMiss
+
+// DiskCache is a Cache keeping every entry in its own file under a directory.
+type DiskCache = cache.Disk
+
+/*
+NewDiskCache returns a Cache that stores its entries under dir, creating it if needed.
+
+Usage:
+
+	cache, err := chew.NewDiskCache(filepath.Join(os.TempDir(), "chew"))
+	if err != nil {
+		log.Fatalf("Error creating cache: %v", err)
+	}
+
+	c := chew.New(chew.Config{Cache: cache, CacheChunks: true})
+*/
+func NewDiskCache(dir string) (*DiskCache, error) {
+	return cache.NewDisk(dir)
+}
+
+/*
+cachedResponse is what's kept of a response so it can be served again when the server answers a
+conditional request with 304 Not Modified.
+*/
+type cachedResponse struct {
+	ContentType  string
+	RobotsTags   []string
+	ETag         string
+	LastModified string
+	Body         []byte
+}
+
+func responseKey(url string) string {
+	return "response:" + url
+}
+
+// loadResponse returns the cached response for url, or nil if there's none.
+func (c *Chew) loadResponse(ctx context.Context, url string) *cachedResponse {
+	data, err := c.config.Cache.Get(ctx, responseKey(url))
+	if err != nil {
+		return nil
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil
+	}
+	return &cached
+}
+
+// addValidators makes req conditional on the cached response having changed.
+func (cached *cachedResponse) addValidators(req *http.Request) {
+	if cached == nil {
+		return
+	}
+	if cached.ETag != "" {
+		req.Header.Set("If-None-Match", cached.ETag)
+	}
+	if cached.LastModified != "" {
+		req.Header.Set("If-Modified-Since", cached.LastModified)
+	}
+}
+
+/*
+storeResponse caches a response along with its body. Responses without validators are skipped since
+they can't be revalidated, and so are the ones the server asked not to be stored.
+*/
+func (c *Chew) storeResponse(ctx context.Context, url string, resp *http.Response, body []byte) {
+	etag, lastModified := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return
+	}
+	if strings.Contains(strings.ToLower(resp.Header.Get("Cache-Control")), "no-store") {
+		return
+	}
+
+	data, err := json.Marshal(cachedResponse{
+		ContentType:  resp.Header.Get("Content-Type"),
+		RobotsTags:   resp.Header.Values("X-Robots-Tag"),
+		ETag:         etag,
+		LastModified: lastModified,
+		Body:         body,
+	})
+	if err != nil {
+		return
+	}
+	_ = c.config.Cache.Put(ctx, responseKey(url), data)
+}
+
+/*
+chunksKey identifies the chunks content is processed into. Besides the content it covers everything
+the chunks depend on, the source they're attributed to, the content type and the options that change
+how content is extracted and split.
+*/
+func (c *Chew) chunksKey(url, contentType string, content []byte) string {
+	options, _ := json.Marshal(struct {
+		Chunking common.ChunkingOptions
+		HTML     common.HTMLOptions
+		Archives common.ArchiveOptions
+	}{c.config.Chunking, c.config.HTML, c.config.Archives})
+
+	h := sha256.New()
+	for _, part := range [][]byte{[]byte(url), []byte(contentType), options, content} {
+		h.Write(part)
+		h.Write([]byte{0})
+	}
+	return "chunks:" + hex.EncodeToString(h.Sum(nil))
+}
+
+/*
+processCached processes content like processContent, but when Config.CacheChunks is set the chunks
+are cached and content that was processed before isn't processed again.
+*/
+func (c *Chew) processCached(ctx context.Context, content []byte, contentType, url string) ([]common.Chunk, error) {
+	if c.config.Cache == nil || !c.config.CacheChunks {
+		return c.processContent(bytes.NewReader(content), contentType, url)
+	}
+
+	key := c.chunksKey(url, contentType, content)
+	if data, err := c.config.Cache.Get(ctx, key); err == nil {
+		var chunks []common.Chunk
+		if err := json.Unmarshal(data, &chunks); err == nil {
+			fetchedAt := time.Now()
+			for i := range chunks {
+				chunks[i].Metadata.FetchedAt = fetchedAt
+			}
+			return chunks, nil
+		}
+	}
+
+	chunks, err := c.processContent(bytes.NewReader(content), contentType, url)
+	if err != nil {
+		// partly processed content isn't cached, the part that failed would never be tried again
+		return chunks, err
+	}
+
+	if data, err := json.Marshal(chunks); err == nil {
+		_ = c.config.Cache.Put(ctx, key, data)
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,81 @@
+package chew
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+func TestProcess_Cache(t *testing.T) {
+	tests := []struct {
+		name          string
+		etag          string
+		cacheChunks   bool
+		wantFull      int32
+		wantProcessed int32
+	}{
+		{name: "revalidated", etag: `"v1"`, wantFull: 1, wantProcessed: 2},
+		{name: "chunks cached", etag: `"v1"`, cacheChunks: true, wantFull: 1, wantProcessed: 1},
+		{name: "no validators", cacheChunks: true, wantFull: 2, wantProcessed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var full, notModified, processed atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.etag != "" {
+					if r.Header.Get("If-None-Match") == tt.etag {
+						notModified.Add(1)
+						w.WriteHeader(http.StatusNotModified)
+						return
+					}
+					w.Header().Set("ETag", tt.etag)
+				}
+				full.Add(1)
+				w.Header().Set("Content-Type", "application/x-test")
+				w.Write([]byte("cached content"))
+			}))
+			defer server.Close()
+
+			cache, err := NewDiskCache(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			c := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 10, Cache: cache, CacheChunks: tt.cacheChunks})
+			c.Registry().RegisterContentType("application/x-test", ProcessorFunc(func(r io.Reader, source string) ([]Chunk, error) {
+				processed.Add(1)
+				content, err := io.ReadAll(r)
+				return []Chunk{{Content: string(content), Source: source}}, err
+			}), 0)
+
+			var results [][]common.Chunk
+			for range 2 {
+				chunks, err := c.Process(context.Background(), []string{server.URL})
+				if err != nil {
+					t.Fatalf("Process() error = %v", err)
+				}
+				for i := range chunks {
+					chunks[i].Metadata.FetchedAt = time.Time{}
+				}
+				results = append(results, chunks)
+			}
+
+			if !reflect.DeepEqual(results[0], results[1]) {
+				t.Errorf("Process() = %+v from the cache, want %+v", results[1], results[0])
+			}
+			if full.Load() != tt.wantFull || full.Load()+notModified.Load() != 2 {
+				t.Errorf("server sent %d full and %d not modified responses, want %d full", full.Load(), notModified.Load(), tt.wantFull)
+			}
+			if processed.Load() != tt.wantProcessed {
+				t.Errorf("content was processed %d times, want %d", processed.Load(), tt.wantProcessed)
+			}
+		})
+	}
+}
@@ -35,7 +35,10 @@ Users of this library are responsible for ensuring their usage complies with app
 package chew
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -44,9 +47,11 @@ import (
 	"sync"
 	"time"
 
+	"github.com/mmatongo/chew/v1/internal/chunker"
 	"github.com/mmatongo/chew/v1/internal/common"
-	"github.com/mmatongo/chew/v1/internal/document"
+	"github.com/mmatongo/chew/v1/internal/sniff"
 	"github.com/mmatongo/chew/v1/internal/text"
+	"github.com/mmatongo/chew/v1/internal/tokenizer"
 	"github.com/mmatongo/chew/v1/internal/transcribe"
 	"github.com/mmatongo/chew/v1/internal/utils"
 	"github.com/temoto/robotstxt"
@@ -54,41 +59,34 @@ import (
 )
 
 const (
-	contentTypeHTML     = "text/html"
-	contentTypeText     = "text/plain"
-	contentTypeXML      = "application/xml"
-	contentTypeTextXML  = "text/xml"
-	contentTypePDF      = "application/pdf"
-	contentTypeCSV      = "text/csv"
-	contentTypeJSON     = "application/json"
-	contentTypeYAML     = "application/x-yaml"
-	contentTypeMarkdown = "text/markdown"
-	contentTypeEPUB     = "application/epub+zip"
-	contentTypeDocx     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
-	contentTypePptx     = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	contentTypeHTML     = common.ContentTypeHTML
+	contentTypeText     = common.ContentTypeText
+	contentTypeXML      = common.ContentTypeXML
+	contentTypeTextXML  = common.ContentTypeTextXML
+	contentTypePDF      = common.ContentTypePDF
+	contentTypeCSV      = common.ContentTypeCSV
+	contentTypeJSON     = common.ContentTypeJSON
+	contentTypeYAML     = common.ContentTypeYAML
+	contentTypeMarkdown = common.ContentTypeMarkdown
+	contentTypeEPUB     = common.ContentTypeEPUB
+	contentTypeDocx     = common.ContentTypeDocx
+	contentTypePptx     = common.ContentTypePptx
+	contentTypeZip      = sniff.ContentTypeZip
+	contentTypeTar      = sniff.ContentTypeTar
+	contentTypeGzip     = sniff.ContentTypeGzip
 )
 
-var contentTypeProcessors = map[string]func(io.Reader, string) ([]common.Chunk, error){
-	contentTypeHTML:     text.ProcessHTML,
-	contentTypeCSV:      text.ProcessCSV,
-	contentTypeJSON:     text.ProcessJSON,
-	contentTypeYAML:     text.ProcessYAML,
-	contentTypeMarkdown: text.ProcessText,
-	contentTypeText:     text.ProcessText,
-	contentTypeXML:      text.ProcessXML,
-	contentTypeTextXML:  text.ProcessXML,
-	contentTypeDocx:     document.ProcessDocx,
-	contentTypePptx:     document.ProcessPptx,
-	contentTypePDF:      document.ProcessPDF,
-	contentTypeEPUB:     document.ProcessEpub,
-}
-
 type Chew struct {
 	config        common.Config
 	httpClient    *http.Client
+	registry      *Registry
+	hostSlots     *hostSlots
+	hostLimiters  *hostLimiters
+	tokenizer     Tokenizer
+	retryPolicy   RetryPolicy
 	rateLimiter   RateLimiter
 	rateLimiterMu sync.RWMutex
-	robotsCache   map[string]*robotstxt.RobotsData
+	robotsCache   map[string]robotsEntry
 	robotsMu      sync.RWMutex
 	lastAccess    map[string]time.Time
 	lastAccessMu  sync.Mutex
@@ -126,12 +124,24 @@ Usage:
 */
 func New(config common.Config) *Chew {
 	c := &Chew{
-		config:      config,
-		robotsCache: make(map[string]*robotstxt.RobotsData),
-		lastAccess:  make(map[string]time.Time),
+		config:       config,
+		registry:     newDefaultRegistry(config),
+		hostSlots:    newHostSlots(config.MaxConcurrencyPerHost),
+		hostLimiters: newHostLimiters(config),
+		tokenizer:    config.Tokenizer,
+		retryPolicy:  retryPolicy(config),
+		robotsCache:  make(map[string]robotsEntry),
+		lastAccess:   make(map[string]time.Time),
 	}
 	c.initHTTPClient()
 
+	// the entries of archives are handed to the processors of c, so it has to exist first
+	registerArchiveProcessor(c.registry, archiveProcessor{c: c})
+
+	if c.tokenizer == nil {
+		c.tokenizer = tokenizer.Estimate{}
+	}
+
 	limit := rate.Every(config.RateLimit)
 	c.rateLimiter = rate.NewLimiter(limit, config.RateBurst)
 
@@ -179,43 +189,170 @@ Config struct contains the configuration options for URL processing.
 
 Fields:
   - UserAgent: The user agent string to use for requests (e.g., "MyBot/1.0 (+https://example.com/bot)")
-  - RetryLimit: Number of retries to attempt in case of failure (e.g., 3)
-  - RetryDelay: Delay between retries (e.g., 5 * time.Second)
-  - CrawlDelay: Delay between requests to the same domain (e.g., 10 * time.Second)
+  - RetryLimit: Number of retries to attempt in case of a transient failure (e.g., 3)
+  - RetryDelay: Delay before the first retry, it doubles with every retry after that (e.g., 5 * time.Second)
+  - RetryPolicy: Decides which failures are retried and when, replaces RetryLimit and RetryDelay, see BackoffPolicy (e.g., chew.BackoffPolicy{MaxRetries: 5, BaseDelay: time.Second})
+  - CrawlDelay: Minimum delay between requests to the same domain, the Crawl-delay of robots.txt is used if it's longer (e.g., 10 * time.Second)
   - ProxyList: List of proxy URLs to use for requests (e.g., []string{"http://proxy1.com", "http://proxy2.com"})
   - RateLimit: Rate limit for requests (e.g., rate.Every(2 * time.Second))
   - RateBurst: Maximum burst size for rate limiting (e.g., 3)
+  - HostRateLimit: Minimum interval between requests to the same host, on top of RateLimit, 0 means no limit (e.g., time.Second)
+  - HostRateBurst: Maximum burst size for the requests to a single host (e.g., 2)
+  - HostRateLimits: Rate limits for specific domains and their subdomains, overriding HostRateLimit and HostRateBurst, see HostRate (e.g., map[string]chew.HostRate{"example.com": {RateLimit: 5 * time.Second}})
   - IgnoreRobotsTxt: Whether to ignore robots.txt rules (e.g., false)
+  - RobotsCacheTTL: How long a robots.txt is cached before it's fetched again, defaults to 24 hours (e.g., time.Hour)
+  - RobotsTags: What to do with content marked noindex by an X-Robots-Tag header or a robots meta tag, see RobotsTagPolicy (e.g., chew.RobotsTagsSkip)
+  - MaxConcurrency: Maximum number of sources processed at the same time, defaults to 10 (e.g., 20)
+  - MaxConcurrencyPerHost: Maximum number of sources processed at the same time per host, 0 means no limit (e.g., 2)
+  - Chunking: How the chunks produced by the processors are split and merged, see ChunkingOptions (e.g., chew.ChunkingOptions{Strategy: chew.ChunkRecursive, MaxChars: 1000})
+  - Tokenizer: Used to count the tokens of each chunk and for ChunkingOptions.MaxTokens, defaults to an estimate (e.g., chew.LoadTokenizer("cl100k_base.tiktoken"))
+  - HTML: How HTML pages and EPUB chapters are extracted, see HTMLOptions (e.g., chew.HTMLOptions{Format: chew.FormatMarkdown})
+  - Cache: Where responses are cached so they're only downloaded again when they've changed, nil disables caching, see Cache (e.g., chew.NewDiskCache("/var/cache/chew"))
+  - CacheChunks: Whether the chunks are cached along with the responses, so content that hasn't changed isn't processed again either, they aren't invalidated by registering processors or changing the Tokenizer (e.g., true)
+  - Files: How file:// directories and glob patterns are expanded into files, see FileOptions (e.g., chew.FileOptions{Include: []string{"*.pdf"}, MaxFileSize: 50 << 20})
+  - Archives: Limits on the ZIP, TAR and gzip archives whose entries are processed, see ArchiveOptions (e.g., chew.ArchiveOptions{MaxDepth: 1, MaxTotalSize: 200 << 20})
 
 Usage:
 
 	config := chew.Config{
-	    UserAgent:       "MyBot/1.0 (+https://example.com/bot)",
-	    RetryLimit:      3,
-	    RetryDelay:      5 * time.Second,
-	    CrawlDelay:      10 * time.Second,
-	    ProxyList:       []string{"http://proxy1.com", "http://proxy2.com"},
-	    RateLimit:       2 * time.Second,
-	    RateBurst:       3,
-	    IgnoreRobotsTxt: false,
+	    UserAgent:             "MyBot/1.0 (+https://example.com/bot)",
+	    RetryLimit:            3,
+	    RetryDelay:            5 * time.Second,
+	    CrawlDelay:            10 * time.Second,
+	    ProxyList:             []string{"http://proxy1.com", "http://proxy2.com"},
+	    RateLimit:             2 * time.Second,
+	    RateBurst:             3,
+	    IgnoreRobotsTxt:       false,
+	    RobotsCacheTTL:        time.Hour,
+	    RobotsTags:            chew.RobotsTagsSkip,
+	    MaxConcurrency:        20,
+	    MaxConcurrencyPerHost: 2,
+	    Chunking:              chew.ChunkingOptions{Strategy: chew.ChunkRecursive, MaxChars: 1000, Overlap: 100},
+	    HTML:                  chew.HTMLOptions{Format: chew.FormatMarkdown},
 	}
 */
 type Config = common.Config
 
 /*
-This is meant as a fallback in case the content type is not recognized and to enforce
-the content type based on the file extension instead of the content type
-returned by the server. i.e. if the server returns text/plain but the file is a markdown file
-the content types are the biggest culprits of this
+HTMLOptions controls how text is extracted from HTML pages and from the chapters of EPUB files.
+
+Only the parts of a page matched by the Include selectors are extracted (the whole body if there are none),
+after removing everything matched by the Exclude selectors. Exclude defaults to "nav", "header" and "footer".
+
+Fields:
+  - Format: The format of the extracted text, FormatText or FormatMarkdown (e.g., chew.FormatMarkdown)
+  - Include: CSS selectors of the content to extract (e.g., []string{"article", "main"})
+  - Exclude: CSS selectors of the content to drop, replaces the defaults when set (e.g., []string{"nav", ".cookie-banner", ".sidebar"})
+  - Domains: Selectors for specific domains and their subdomains, the most specific domain wins (e.g., map[string]chew.Selectors{"docs.example.com": {Include: []string{"article.main-content"}}})
+  - Readability: Keep only the main article of what's selected, dropping boilerplate such as sidebars and comments, like Mozilla's Readability (e.g., true)
+  - DocumentHeader: Start every HTML page with a chunk holding its title, description, canonical URL and language (e.g., true)
+  - Tables: How tables are extracted, TableMarkdown for a GFM table per table or TableRows for a chunk per row (e.g., chew.TableRows)
+
+Usage:
+
+	config := chew.Config{
+	    HTML: chew.HTMLOptions{
+	        Exclude: []string{"nav", "header", "footer", ".cookie-banner"},
+	        Domains: map[string]chew.Selectors{
+	            "docs.example.com": {Include: []string{"article.main-content"}},
+	        },
+	    },
+	}
 */
-var validExtensions = map[string]func(io.Reader, string) ([]common.Chunk, error){
-	".md":   text.ProcessText,
-	".csv":  text.ProcessCSV,
-	".json": text.ProcessJSON,
-	".yaml": text.ProcessYAML,
-	".html": text.ProcessHTML,
-	".epub": document.ProcessEpub,
-}
+type HTMLOptions = common.HTMLOptions
+
+/*
+Selectors overrides the selectors of HTMLOptions for a domain, only the fields that are set replace
+the global ones.
+
+Fields:
+  - Include: CSS selectors of the content to extract (e.g., []string{"article.main-content"})
+  - Exclude: CSS selectors of the content to drop (e.g., []string{".sidebar"})
+*/
+type Selectors = common.Selectors
+
+/*
+TableFormat selects how HTML tables are extracted, see HTMLOptions. Cells spanning several rows or columns
+are repeated in every position they cover so rows can be read on their own.
+*/
+type TableFormat = common.TableFormat
+
+const (
+	// TableMarkdown renders each table as a GFM table in a chunk of its own.
+	TableMarkdown = common.TableMarkdown
+	// TableRows renders each row of a table as a chunk of "header: value" lines.
+	TableRows = common.TableRows
+)
+
+// OutputFormat selects the format of the text extracted from HTML, see HTMLOptions.
+type OutputFormat = common.OutputFormat
+
+const (
+	// FormatText extracts the plain text of paragraphs, headings and list items.
+	FormatText = common.FormatText
+	// FormatMarkdown renders the page as markdown, keeping headings, nested lists, links, code blocks and tables.
+	FormatMarkdown = common.FormatMarkdown
+)
+
+/*
+ChunkingOptions controls how the chunks produced by the processors are regrouped before they're returned.
+By default chunks are returned exactly as the processors produce them, which varies a lot between content types.
+
+Chunks are never merged across structural boundaries reported by the processors, i.e. different sources,
+PDF pages, PPTX slides or HTML sections, but within one they're split along the strategy's boundaries and
+packed back together up to the size limit.
+
+Fields:
+  - Strategy: How content is split, one of ChunkByParagraph, ChunkBySentence or ChunkRecursive (e.g., chew.ChunkByParagraph)
+  - MaxChars: Maximum number of characters per chunk, 0 means no limit (e.g., 1000)
+  - MaxTokens: Maximum number of tokens per chunk as counted by Config.Tokenizer, 0 means no limit (e.g., 512)
+  - Overlap: How much of the end of the previous chunk is repeated, in tokens if MaxTokens is set and characters otherwise (e.g., 100)
+*/
+type ChunkingOptions = common.ChunkingOptions
+
+// ChunkStrategy selects how content is split by the chunker, see ChunkingOptions.
+type ChunkStrategy = common.ChunkStrategy
+
+const (
+	// ChunkNone leaves the chunks exactly as the processors produce them.
+	ChunkNone = common.ChunkNone
+	// ChunkByParagraph splits on blank lines and packs whole paragraphs together.
+	ChunkByParagraph = common.ChunkByParagraph
+	// ChunkBySentence splits on sentence boundaries and packs whole sentences together.
+	ChunkBySentence = common.ChunkBySentence
+	// ChunkRecursive keeps each processor chunk whole where possible and only splits what doesn't fit.
+	ChunkRecursive = common.ChunkRecursive
+)
+
+/*
+Chunk is a single piece of content extracted from a source. The Source field holds the URL
+or file path the content was extracted from and Metadata holds whatever else is known about it.
+*/
+type Chunk = common.Chunk
+
+/*
+Metadata describes where a Chunk came from. Processors only fill in the fields that make sense
+for their content type, the rest are left as their zero value. ContentType is filled in for them
+with the content type the processor was picked for.
+
+Fields:
+  - ContentType: The MIME type of the source (e.g., "application/pdf")
+  - Title: The title of the document the chunk belongs to (e.g., the HTML <title> or the EPUB title)
+  - Section: The path of headings (or XML elements) leading up to the chunk (e.g., []string{"Install", "Linux"})
+  - Page: The page of a PDF or the slide of a PPTX the chunk was found on, starting at 1
+  - Chapter: The chapter of an EPUB the chunk was found in, starting at 1
+  - Index: The position of the chunk within its source, starting at 0 (e.g., the row of a CSV file)
+  - ByteOffset: Where the chunk starts in the source, for formats where that's known (CSV and XML)
+  - FetchedAt: When the source was fetched or read
+  - Language: The language declared by the source (e.g., "en")
+  - Tokens: Number of tokens in the content as counted by Config.Tokenizer
+  - Description: The description of the HTML page the chunk belongs to (i.e. <meta name="description">)
+  - Canonical: The canonical URL of the HTML page, relative to where redirects ended up, useful to dedupe pages reachable from more than one URL
+  - OpenGraph: The Open Graph properties of the HTML page (e.g., map[string]string{"og:type": "article"})
+  - JSONLD: The application/ld+json blocks of the HTML page, blocks that aren't valid JSON are dropped
+  - NoIndex: Set when the source is marked noindex and Config.RobotsTags is RobotsTagsFlag
+*/
+type Metadata = common.Metadata
 
 /*
 SetHTTPClient allows you to set a custom http.Client to use for making requests.
@@ -247,26 +384,16 @@ func (c *Chew) initHTTPClient() {
 }
 
 /*
-For content types that can also return text/plain as their content types we need to manually check
-their extension to properly process them. I feel like this could be done better but this is my solution for now.
-*/
-func getProcessor(contentType, url string) (func(io.Reader, string) ([]common.Chunk, error), error) {
-	for key, proc := range contentTypeProcessors {
-		if strings.Contains(contentType, key) {
-			return proc, nil
-		}
-	}
-
-	ext, err := utils.GetFileExtension(url)
-	if err != nil {
-		return nil, fmt.Errorf("couldn't get file extension from url %s: %s", url, err)
-	}
+Registry returns the processor registry used by this instance. It comes populated with the
+built-in processors and can be used to register new ones or to replace them.
 
-	if proc, ok := validExtensions[ext]; ok {
-		return proc, nil
-	}
+Usage:
 
-	return nil, fmt.Errorf("unsupported content type: %s", contentType)
+	c := chew.New(config)
+	c.Registry().RegisterExtension(".log", chew.ProcessorFunc(myLogProcessor), 0)
+*/
+func (c *Chew) Registry() *Registry {
+	return c.registry
 }
 
 /*
@@ -290,77 +417,168 @@ Usage:
 	}
 */
 func (c *Chew) Process(ctx context.Context, urls []string) ([]common.Chunk, error) {
-	var (
-		result []common.Chunk
-		mu     sync.Mutex
-		errCh  = make(chan error, len(urls))
-		resCh  = make(chan []common.Chunk, len(urls))
-	)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	for _, url := range urls {
-		go func(url string) {
-			select {
-			case <-ctx.Done():
-				errCh <- ctx.Err()
-				return
-			default:
-				c.rateLimiterMu.RLock()
-				rateLimiter := c.rateLimiter
-				c.rateLimiterMu.RUnlock()
-
-				if err := rateLimiter.Wait(ctx); err != nil {
-					errCh <- fmt.Errorf("rate limit exceeded for %s: %w", url, err)
-					return
-				}
+	var result []common.Chunk
+	for res := range c.ProcessStream(ctx, urls) {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		result = append(result, res.Chunk)
+	}
 
-				if !c.config.IgnoreRobotsTxt {
-					allowed, crawlDelay, err := c.getRobotsTxtInfo(url)
-					if err != nil {
-						errCh <- fmt.Errorf("checking robots.txt for %s: %w", url, err)
-						return
-					}
-					if !allowed {
-						errCh <- fmt.Errorf("access to %s is disallowed by robots.txt", url)
-						return
-					}
-					if err := c.respectCrawlDelay(ctx, url, crawlDelay); err != nil {
-						errCh <- fmt.Errorf("respecting crawl delay for %s: %w", url, err)
-						return
-					}
-				}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+/*
+Result is a single item emitted by ProcessStream. It either holds a Chunk or an Err and is always
+tagged with the URL (or file path) it originated from, exactly as it was passed in. Attempts is the
+number of times the source was fetched, including retries.
+*/
+type Result struct {
+	URL      string
+	Chunk    Chunk
+	Err      error
+	Attempts int
+}
+
+/*
+ProcessStream takes a list of URLs and emits their chunks over a channel as soon as they become
+available. A failure to process one URL is reported as a Result with a non-nil Err and doesn't
+stop the remaining URLs from being processed. A URL that was only partly processed, e.g. an archive
+with an entry that failed, has the chunks of the rest emitted before its error, see ErrPartialContent.
+
+The channel is closed once every URL has been processed or the context is cancelled, so callers
+should keep reading from it until it's closed.
+
+This function is safe for concurrent use.
+
+Usage:
+
+	for res := range c.ProcessStream(ctx, urls) {
+		if res.Err != nil {
+			log.Printf("Error processing %s: %v", res.URL, res.Err)
+			continue
+		}
+		index(res.Chunk)
+	}
+*/
+func (c *Chew) ProcessStream(ctx context.Context, urls []string) <-chan Result {
+	out := make(chan Result)
+
+	send := func(res Result) bool {
+		select {
+		case out <- res:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
 
-				chunks, err := c.processWithRetry(ctx, url)
-				if err != nil {
-					errCh <- fmt.Errorf("processing %s: %w", url, err)
+	go func() {
+		defer close(out)
+
+		for sr := range c.run(ctx, urls) {
+			// partly processed sources have chunks and an error
+			for _, chunk := range sr.chunks {
+				if !send(Result{URL: sr.url, Chunk: chunk, Attempts: sr.info.attempts}) {
 					return
 				}
+			}
 
-				resCh <- chunks
+			if sr.err != nil {
+				if !send(Result{URL: sr.url, Err: sr.err, Attempts: sr.info.attempts}) {
+					return
+				}
 			}
-		}(url)
+		}
+	}()
+
+	return out
+}
+
+// sourceResult is the outcome of processing a single source.
+type sourceResult struct {
+	url      string
+	chunks   []common.Chunk
+	err      error
+	info     fetchInfo
+	duration time.Duration
+}
+
+/*
+fetchInfo collects details about how a single source was fetched. When collectLinks is set the
+links of HTML pages are extracted into links, for the crawler.
+*/
+type fetchInfo struct {
+	attempts     int
+	statusCode   int
+	collectLinks bool
+	links        []string
+}
+
+/*
+processSource runs a single URL through the politeness checks (rate limiting, robots.txt and
+crawl delays) before processing it with retries. Local files don't go through the checks, there's
+no server to be polite to.
+*/
+func (c *Chew) processSource(ctx context.Context, url string, info *fetchInfo) ([]common.Chunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	for i := 0; i < len(urls); i++ {
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		case err := <-errCh:
+	if !strings.HasPrefix(url, "file://") {
+		if err := c.politeWait(ctx, url); err != nil {
 			return nil, err
-		case chunks := <-resCh:
-			mu.Lock()
-			result = append(result, chunks...)
-			mu.Unlock()
 		}
 	}
 
-	return result, nil
+	chunks, err := c.processWithRetry(ctx, url, info)
+	if err != nil {
+		return chunks, fmt.Errorf("processing %s: %w", url, err)
+	}
+
+	return chunks, nil
+}
+
+// politeWait waits for the rate limiter, checks robots.txt and respects the crawl delay before url is fetched.
+func (c *Chew) politeWait(ctx context.Context, url string) error {
+	c.rateLimiterMu.RLock()
+	rateLimiter := c.rateLimiter
+	c.rateLimiterMu.RUnlock()
+
+	if err := rateLimiter.Wait(ctx); err != nil {
+		return rateLimitError(ctx, fmt.Errorf("%s: %w", url, err))
+	}
+
+	if c.config.IgnoreRobotsTxt {
+		return nil
+	}
+
+	allowed, crawlDelay, err := c.getRobotsTxtInfo(ctx, url)
+	if err != nil {
+		return fmt.Errorf("checking robots.txt for %s: %w", url, err)
+	}
+	if !allowed {
+		return fmt.Errorf("access to %s: %w", url, ErrDisallowedByRobots)
+	}
+	if err := c.respectCrawlDelay(ctx, url, crawlDelay); err != nil {
+		return fmt.Errorf("respecting crawl delay for %s: %w", url, err)
+	}
+
+	return nil
 }
 
 /*
 processURL handles the actual processing of a single URL or file
 file paths are processed directly while URLs are fetched and processed
 */
-func (c *Chew) processURL(ctx context.Context, url string) ([]common.Chunk, error) {
+func (c *Chew) processURL(ctx context.Context, url string, info *fetchInfo) ([]common.Chunk, error) {
 	// if the url is a file path we can just open the file and process it directly
 	if filePath, found := strings.CutPrefix(url, "file://"); found {
 		file, err := utils.OpenFile(filePath)
@@ -369,23 +587,28 @@ func (c *Chew) processURL(ctx context.Context, url string) ([]common.Chunk, erro
 		}
 		defer file.Close()
 
-		ext, _ := utils.GetFileExtension(filePath)
+		if limit := c.config.Files.MaxFileSize; limit > 0 {
+			if stat, err := file.Stat(); err == nil && stat.Size() > limit {
+				return nil, ErrFileTooLarge
+			}
+		}
+
 		/*
-			Will leave this in here for now, but I think it's better to just check the file extension
-			instead of the content type returned.
+			Files don't come with a content type so we guess one from the extension,
+			the registry falls back to the extension itself and then to sniffing.
+			The charset the guess may come with is dropped, it says nothing about the file.
 		*/
-		contentType := utils.GetFileContentType(file)
+		contentType := mediaType(utils.GetFileContentType(file))
 
-		proc, err := getProcessor(contentType, filePath)
-		if err != nil {
-			proc, ok := validExtensions[ext]
-			if !ok {
-				return nil, fmt.Errorf("unsupported file type: %s", ext)
-			}
-			return proc(file, url)
+		if c.config.Cache == nil || !c.config.CacheChunks {
+			return c.processContent(file, contentType, url)
 		}
 
-		return proc(file, url)
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return nil, fmt.Errorf("reading file: %w", err)
+		}
+		return c.processCached(ctx, content, contentType, url)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -395,57 +618,294 @@ func (c *Chew) processURL(ctx context.Context, url string) ([]common.Chunk, erro
 
 	req.Header.Set("User-Agent", c.config.UserAgent)
 
+	var cached *cachedResponse
+	if c.config.Cache != nil {
+		cached = c.loadResponse(ctx, url)
+		cached.addValidators(req)
+	}
+
+	if err := c.hostLimiters.wait(ctx, req.URL.Host); err != nil {
+		return nil, rateLimitError(ctx, err)
+	}
+
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("making request: %w", err)
 	}
 	defer resp.Body.Close()
 
-	contentType := resp.Header.Get("Content-Type")
+	c.hostLimiters.observe(req.URL.Host, resp.StatusCode)
+	info.statusCode = resp.StatusCode
+
+	var (
+		content     io.Reader = resp.Body
+		contentType           = resp.Header.Get("Content-Type")
+		robotsTags            = resp.Header.Values("X-Robots-Tag")
+		notModified           = resp.StatusCode == http.StatusNotModified && cached != nil
+	)
+	if notModified {
+		content, contentType, robotsTags = bytes.NewReader(cached.Body), cached.ContentType, cached.RobotsTags
+	} else if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newHTTPError(resp, url)
+	}
+
+	var directives robotsDirectives
+	if c.config.RobotsTags != RobotsTagsIgnore {
+		directives = parseXRobotsTag(robotsTags, c.config.UserAgent)
+		// without links to collect there's no point in downloading the page
+		if directives.noIndex && c.config.RobotsTags == RobotsTagsSkip && (!info.collectLinks || directives.noFollow) {
+			return nil, ErrNoIndex
+		}
+	}
+
+	/*
+		Relative URLs in the content, like the canonical URL of a page, are relative to where redirects
+		ended up, so the content is processed as served from there and its chunks are attributed to url
+		afterwards. Transports other than http.Transport may not say where that is.
+	*/
+	served := url
+	if resp.Request != nil && resp.Request.URL.String() != req.URL.String() {
+		served = resp.Request.URL.String()
+	}
+
+	if !info.collectLinks && c.config.RobotsTags == RobotsTagsIgnore && c.config.Cache == nil {
+		chunks, err := c.processContent(content, contentType, served)
+		attribute(chunks, served, url)
+		return chunks, err
+	}
 
-	processor, err := getProcessor(contentType, url)
+	// the page is read twice, once for the links and robots meta tags and once by the processor
+	body, err := io.ReadAll(content)
 	if err != nil {
+		return nil, fmt.Errorf("reading response: %w", err)
+	}
+
+	if c.config.Cache != nil && !notModified {
+		c.storeResponse(ctx, url, resp, body)
+	}
+
+	if mediaType(contentType) == contentTypeHTML || (contentType == "" && mediaType(http.DetectContentType(body)) == contentTypeHTML) {
+		page, err := text.ParsePage(bytes.NewReader(body), served)
+		if err != nil {
+			return nil, fmt.Errorf("parsing page: %w", err)
+		}
+
+		if c.config.RobotsTags != RobotsTagsIgnore {
+			meta := parseMetaRobots(page.Meta, c.config.UserAgent)
+			directives.noIndex = directives.noIndex || meta.noIndex
+			directives.noFollow = directives.noFollow || meta.noFollow
+		}
+
+		if info.collectLinks && !directives.noFollow {
+			// set rather than appended to, a retry would find the same links again
+			info.links = nil
+			for _, link := range page.Links {
+				if link.NoFollow && c.config.RobotsTags != RobotsTagsIgnore {
+					continue
+				}
+				info.links = append(info.links, link.URL)
+			}
+		}
+	}
+
+	if directives.noIndex && c.config.RobotsTags == RobotsTagsSkip {
+		return nil, ErrNoIndex
+	}
+
+	chunks, err := c.processCached(ctx, body, contentType, served)
+	if err != nil && !errors.Is(err, ErrPartialContent) {
 		return nil, err
 	}
+	attribute(chunks, served, url)
+
+	if directives.noIndex {
+		for i := range chunks {
+			chunks[i].Metadata.NoIndex = true
+		}
+	}
+
+	return chunks, err
+}
+
+// attribute points the sources of chunks processed as served from served back to url, the URL they were asked for by.
+func attribute(chunks []common.Chunk, served, url string) {
+	if served == url {
+		return
+	}
+	for i := range chunks {
+		if rest, ok := strings.CutPrefix(chunks[i].Source, served); ok {
+			chunks[i].Source = url + rest
+		}
+	}
+}
 
-	return processor(resp.Body, url)
+/*
+processContent picks a processor for the content from the registry and runs it. The first
+few bytes are buffered so that sniffers can take a look at them before anything is consumed.
+
+Processors fill in what they know about each chunk, anything that only the caller knows
+(i.e. when the content was fetched) is filled in here.
+*/
+func (c *Chew) processContent(r io.Reader, contentType, source string) ([]common.Chunk, error) {
+	return c.processHinted(r, ContentHint{MIME: contentType, Source: source})
 }
 
-func (c *Chew) getRobotsTxtInfo(urlStr string) (bool, time.Duration, error) {
+/*
+processHinted is processContent for content whose name, used to look up a processor by extension,
+isn't its source.
+*/
+func (c *Chew) processHinted(r io.Reader, hint ContentHint) ([]common.Chunk, error) {
+	name := hint.Filename
+	if name == "" {
+		name = hint.Source
+	}
+
+	fetchedAt := time.Now()
+
+	proc, contentType, content, err := c.resolve(r, hint.MIME, name)
+	if err != nil {
+		return nil, err
+	}
+
+	// the chunks that could be extracted are kept when only part of the content failed
+	chunks, err := proc.Process(content, hint.Source)
+	if err != nil && !errors.Is(err, ErrPartialContent) {
+		return nil, err
+	}
+
+	for i := range chunks {
+		if chunks[i].Metadata.ContentType == "" {
+			chunks[i].Metadata.ContentType = mediaType(contentType)
+		}
+		if chunks[i].Metadata.FetchedAt.IsZero() {
+			chunks[i].Metadata.FetchedAt = fetchedAt
+		}
+	}
+
+	chunks = chunker.Split(chunks, c.config.Chunking, c.tokenizer)
+	for i := range chunks {
+		chunks[i].Metadata.Tokens = c.tokenizer.Count(chunks[i].Content)
+	}
+
+	return chunks, err
+}
+
+/*
+resolve looks up the processor for content given its content type and name, and returns it along with
+the content type it was picked for (that of the extension or the sniffed content when it was picked by
+either) and the content to hand it, transcoded to UTF-8 if it's text.
+*/
+func (c *Chew) resolve(r io.Reader, contentType, name string) (Processor, string, io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(sniffLen)
+
+	reg, err := c.registry.lookup(contentType, name, head)
+	if sniff.IsZip(head) && (err != nil || isGenericZip(contentType)) {
+		// the entries telling ZIP based formats apart can be anywhere in the archive
+		content, readErr := io.ReadAll(br)
+		if readErr != nil {
+			return nil, "", nil, fmt.Errorf("reading content: %w", readErr)
+		}
+		if detected := sniff.DetectZip(bytes.NewReader(content), int64(len(content))); detected != "" {
+			contentType = detected
+			reg, err = c.registry.lookup(contentType, name, head)
+		}
+		br = bufio.NewReader(bytes.NewReader(content))
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+	matched := matchedType(reg, contentType, head)
+
+	var content io.Reader = br
+	if isText(matched, name, head) {
+		// the charset is a parameter of the content type as given, it's dropped from the matched one
+		content = text.NewUTF8Reader(br, contentType)
+	}
+
+	return reg.processor, matched, content, nil
+}
+
+// defaultRobotsCacheTTL is used when Config.RobotsCacheTTL isn't set.
+const defaultRobotsCacheTTL = 24 * time.Hour
+
+type robotsEntry struct {
+	data      *robotstxt.RobotsData
+	fetchedAt time.Time
+}
+
+/*
+getRobotsTxtInfo reports whether the user agent is allowed to fetch urlStr and how long to wait
+between requests to its host, which is the larger of the Crawl-delay of the matching robots.txt
+group and Config.CrawlDelay.
+*/
+func (c *Chew) getRobotsTxtInfo(ctx context.Context, urlStr string) (bool, time.Duration, error) {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
 		return false, 0, err
 	}
 
+	robotsData := c.getRobotsData(ctx, parsedURL)
+	if robotsData == nil {
+		return true, c.config.CrawlDelay, nil
+	}
+
+	allowed := robotsData.TestAgent(parsedURL.Path, c.config.UserAgent)
+	crawlDelay := max(robotsData.FindGroup(c.config.UserAgent).CrawlDelay, c.config.CrawlDelay)
+
+	return allowed, crawlDelay, nil
+}
+
+/*
+getRobotsData returns the robots.txt of the host of parsedURL, or nil if it couldn't be fetched.
+It's fetched with the configured http.Client and User-Agent and cached for Config.RobotsCacheTTL.
+*/
+func (c *Chew) getRobotsData(ctx context.Context, parsedURL *url.URL) *robotstxt.RobotsData {
 	robotsURL := fmt.Sprintf("%s://%s/robots.txt", parsedURL.Scheme, parsedURL.Host)
 
+	ttl := c.config.RobotsCacheTTL
+	if ttl <= 0 {
+		ttl = defaultRobotsCacheTTL
+	}
+
 	c.robotsMu.RLock()
-	robotsData, exists := c.robotsCache[robotsURL]
+	entry, exists := c.robotsCache[robotsURL]
 	c.robotsMu.RUnlock()
 
-	if !exists {
-		resp, err := http.Get(robotsURL)
-		if err != nil {
-			return true, c.config.CrawlDelay, nil
-		}
-		defer resp.Body.Close()
+	if exists && time.Since(entry.fetchedAt) < ttl {
+		return entry.data
+	}
 
-		robotsData, err = robotstxt.FromResponse(resp)
-		if err != nil {
-			return true, c.config.CrawlDelay, nil
-		}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// a stale copy is better than nothing while the host is unreachable
+		return entry.data
+	}
+	defer resp.Body.Close()
 
-		c.robotsMu.Lock()
-		c.robotsCache[robotsURL] = robotsData
-		c.robotsMu.Unlock()
+	robotsData, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		return entry.data
 	}
 
-	allowed := robotsData.TestAgent(parsedURL.Path, c.config.UserAgent)
+	c.robotsMu.Lock()
+	c.robotsCache[robotsURL] = robotsEntry{data: robotsData, fetchedAt: time.Now()}
+	c.robotsMu.Unlock()
 
-	return allowed, c.config.CrawlDelay, nil
+	return robotsData
 }
 
-// respectCrawlDelay ensures that subsequent requests to the same domain respect the specified crawl delay.
+/*
+respectCrawlDelay ensures that subsequent requests to the same domain respect the specified crawl delay.
+The time of the next request is reserved before waiting for it, so that concurrent requests to a domain
+are spaced out one after the other instead of all waiting for the same one.
+*/
 func (c *Chew) respectCrawlDelay(ctx context.Context, urlStr string, delay time.Duration) error {
 	parsedURL, err := url.Parse(urlStr)
 	if err != nil {
@@ -455,45 +915,42 @@ func (c *Chew) respectCrawlDelay(ctx context.Context, urlStr string, delay time.
 	domain := parsedURL.Hostname()
 
 	c.lastAccessMu.Lock()
-	lastAccess, exists := c.lastAccess[domain]
-	if exists {
-		timeToWait := time.Until(lastAccess.Add(delay))
-		if timeToWait > 0 {
-			c.lastAccessMu.Unlock()
-			select {
-			case <-time.After(timeToWait):
-			case <-ctx.Done():
-				return ctx.Err()
-			}
-			c.lastAccessMu.Lock()
-		}
+	next := time.Now()
+	if lastAccess, exists := c.lastAccess[domain]; exists && lastAccess.Add(delay).After(next) {
+		next = lastAccess.Add(delay)
 	}
-
-	c.lastAccess[domain] = time.Now()
+	c.lastAccess[domain] = next
 	c.lastAccessMu.Unlock()
+
+	if timeToWait := time.Until(next); timeToWait > 0 {
+		select {
+		case <-time.After(timeToWait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
 	return nil
 }
 
-func (c *Chew) processWithRetry(ctx context.Context, url string) ([]common.Chunk, error) {
-	var (
-		chunks []common.Chunk
-		err    error
-	)
-
-	var retries int
+func (c *Chew) processWithRetry(ctx context.Context, url string, info *fetchInfo) ([]common.Chunk, error) {
 	for {
-		chunks, err = c.processURL(ctx, url)
+		info.attempts++
+		chunks, err := c.processURL(ctx, url, info)
 		if err == nil {
 			return chunks, nil
 		}
-		if retries > c.config.RetryLimit {
-			break
+
+		delay, retry := c.retryPolicy.Retry(info.attempts, err)
+		if !retry || ctx.Err() != nil {
+			// partly processed content comes with the chunks of the part that was
+			return chunks, err
 		}
-		retries++
-		c.wait(ctx, c.config.RetryDelay)
-	}
 
-	return nil, err
+		c.wait(ctx, delay)
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
 }
 
 func (c *Chew) wait(ctx context.Context, d time.Duration) {
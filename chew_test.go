@@ -11,11 +11,12 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/mmatongo/chew/v1/internal/common"
-	"github.com/mmatongo/chew/v1/internal/text"
 	"golang.org/x/time/rate"
 )
 
@@ -27,6 +28,25 @@ func mockProcessor(r io.Reader, url string) ([]common.Chunk, error) {
 	return []common.Chunk{{Content: string(content), Source: url}}, nil
 }
 
+/*
+stripMetadata clears the metadata filled in by the pipeline (the fetch time and token count)
+so chunks can be compared, it also makes sure it was actually set in the first place.
+*/
+func stripMetadata(t *testing.T, chunks []common.Chunk) []common.Chunk {
+	t.Helper()
+	for i := range chunks {
+		if chunks[i].Metadata.FetchedAt.IsZero() {
+			t.Errorf("chunk %d from %s has no fetch time", i, chunks[i].Source)
+		}
+		if chunks[i].Metadata.Tokens == 0 && chunks[i].Content != "" {
+			t.Errorf("chunk %d from %s has no token count", i, chunks[i].Source)
+		}
+		chunks[i].Metadata.FetchedAt = time.Time{}
+		chunks[i].Metadata.Tokens = 0
+	}
+	return chunks
+}
+
 type mockTransport struct {
 	response *http.Response
 	err      error
@@ -46,13 +66,9 @@ func (m *mockRateLimiter) Wait(ctx context.Context) error {
 
 func Test_processURL(t *testing.T) {
 	originalHTTPClient := http.DefaultClient
-	originalContentTypeProcessors := contentTypeProcessors
-	originalValidExtensions := validExtensions
 
 	defer func() {
 		http.DefaultClient = originalHTTPClient
-		contentTypeProcessors = originalContentTypeProcessors
-		validExtensions = originalValidExtensions
 	}()
 
 	mockClient := &http.Client{
@@ -70,14 +86,11 @@ func Test_processURL(t *testing.T) {
 	chew.SetHTTPClient(mockClient)
 	defer chew.SetHTTPClient(nil)
 
-	contentTypeProcessors = map[string]func(io.Reader, string) ([]common.Chunk, error){
-		"text/html":  mockProcessor,
-		"text/plain": mockProcessor,
-	}
-	validExtensions = map[string]func(io.Reader, string) ([]common.Chunk, error){
-		".html": mockProcessor,
-		".txt":  mockProcessor,
-	}
+	chew.registry = NewRegistry()
+	chew.registry.RegisterContentType("text/html", ProcessorFunc(mockProcessor), 0)
+	chew.registry.RegisterContentType("text/plain", ProcessorFunc(mockProcessor), 0)
+	chew.registry.RegisterExtension(".html", ProcessorFunc(mockProcessor), 0)
+	chew.registry.RegisterExtension(".txt", ProcessorFunc(mockProcessor), 0)
 
 	tempDir := t.TempDir()
 	testHTMLPath := filepath.Join(tempDir, "test.html")
@@ -108,19 +121,19 @@ func Test_processURL(t *testing.T) {
 		{
 			name:    "success",
 			url:     "https://example.com/page.html",
-			want:    []common.Chunk{{Content: "Test content", Source: "https://example.com/page.html"}},
+			want:    []common.Chunk{{Content: "Test content", Source: "https://example.com/page.html", Metadata: common.Metadata{ContentType: "text/html"}}},
 			wantErr: false,
 		},
 		{
 			name:    "success html",
 			url:     "file://" + testHTMLPath,
-			want:    []common.Chunk{{Content: "html content", Source: "file://" + testHTMLPath}},
+			want:    []common.Chunk{{Content: "html content", Source: "file://" + testHTMLPath, Metadata: common.Metadata{ContentType: "text/html"}}},
 			wantErr: false,
 		},
 		{
 			name:    "success txt",
 			url:     "file://" + testTXTPath,
-			want:    []common.Chunk{{Content: "text content", Source: "file://" + testTXTPath}},
+			want:    []common.Chunk{{Content: "text content", Source: "file://" + testTXTPath, Metadata: common.Metadata{ContentType: "text/plain"}}},
 			wantErr: false,
 		},
 		{
@@ -139,11 +152,12 @@ func Test_processURL(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := chew.processURL(ctx, tt.url)
+			got, err := chew.processURL(ctx, tt.url, &fetchInfo{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("processURL() error = %v, wantErr %v", err, tt.wantErr)
 				return
 			}
+			got = stripMetadata(t, got)
 			if !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("processURL() = %v, want %v", got, tt.want)
 			}
@@ -151,72 +165,49 @@ func Test_processURL(t *testing.T) {
 	}
 }
 
-func Test_getProcessor(t *testing.T) {
-	type args struct {
-		contentType string
-		url         string
-	}
+func Test_processURL_Redirect(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/docs/page/", http.StatusMovedPermanently)
+		case "/docs/page/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><link rel="canonical" href="intro"></head><body><p>Moved.</p></body></html>`))
+		}
+	}))
+	defer server.Close()
+
 	tests := []struct {
-		name    string
-		args    args
-		want    func(io.Reader, string) ([]common.Chunk, error)
-		wantErr bool
+		name   string
+		config Config
 	}{
 		{
-			name: "success",
-			args: args{
-				contentType: "text/html",
-				url:         "https://example.com/page.html",
-			},
-			want:    mockProcessor,
-			wantErr: false,
+			name:   "processed as read",
+			config: Config{},
 		},
 		{
-			name: "unknown content type",
-			args: args{
-				contentType: "octet/stream",
-				url:         "https://example.com/page.html",
-			},
-			want:    text.ProcessHTML,
-			wantErr: false,
-		},
-		{
-			name: "unsupported content type",
-			args: args{
-				contentType: "application/octet-stream",
-				url:         "https://example.com/page.htt",
-			},
-			want:    nil,
-			wantErr: true,
-		},
-		{
-			name: "no extension",
-			args: args{
-				contentType: "octet/stream",
-				url:         "https://example.com/page",
-			},
-			want:    nil,
-			wantErr: true,
+			name:   "processed after looking for robots meta tags",
+			config: Config{RobotsTags: RobotsTagsSkip},
 		},
 	}
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := getProcessor(tt.args.contentType, tt.args.url)
-			if (err != nil) != tt.wantErr {
-				t.Errorf("getProcessor() error = %v, wantErr %v", err, tt.wantErr)
-				return
-			}
+			tt.config.IgnoreRobotsTxt = true
+			chew := New(tt.config)
 
-			if got == nil && tt.want != nil {
-				t.Errorf("getProcessor() returned nil, want non-nil")
-			} else if got != nil && tt.want == nil {
-				t.Errorf("getProcessor() returned non-nil, want nil")
-			} else if got != nil {
-				gotType := reflect.TypeOf(got)
-				wantType := reflect.TypeOf(tt.want)
-				if gotType != wantType {
-					t.Errorf("getProcessor() returned function of type %v, want %v", gotType, wantType)
-				}
+			got, err := chew.processURL(context.Background(), server.URL+"/old", &fetchInfo{})
+			if err != nil {
+				t.Fatalf("processURL() error = %v", err)
+			}
+			if len(got) != 1 {
+				t.Fatalf("processURL() = %+v, want 1 chunk", got)
+			}
+			if got[0].Source != server.URL+"/old" {
+				t.Errorf("processURL() source = %q, want the URL as requested", got[0].Source)
+			}
+			if want := server.URL + "/docs/page/intro"; got[0].Metadata.Canonical != want {
+				t.Errorf("processURL() canonical = %q, want %q", got[0].Metadata.Canonical, want)
 			}
 		})
 	}
@@ -249,7 +240,7 @@ func TestProcess(t *testing.T) {
 
 	containsChunk := func(chunks []common.Chunk, chunk common.Chunk) bool {
 		for _, c := range chunks {
-			if c.Content == chunk.Content && c.Source == chunk.Source {
+			if reflect.DeepEqual(c, chunk) {
 				return true
 			}
 		}
@@ -288,7 +279,7 @@ func TestProcess(t *testing.T) {
 				urls: []string{server.URL + "/text"},
 			},
 			want: []common.Chunk{
-				{Content: "A plain text file.", Source: server.URL + "/text"},
+				{Content: "A plain text file.", Source: server.URL + "/text", Metadata: common.Metadata{ContentType: "text/plain"}},
 			},
 			wantErr: false,
 		},
@@ -298,7 +289,7 @@ func TestProcess(t *testing.T) {
 				urls: []string{server.URL + "/html"},
 			},
 			want: []common.Chunk{
-				{Content: "An HTML file.", Source: server.URL + "/html"},
+				{Content: "An HTML file.", Source: server.URL + "/html", Metadata: common.Metadata{ContentType: "text/html"}},
 			},
 			wantErr: false,
 		},
@@ -308,7 +299,7 @@ func TestProcess(t *testing.T) {
 				urls: []string{server.URL + "/markdown"},
 			},
 			want: []common.Chunk{
-				{Content: "# A Markdown file", Source: server.URL + "/markdown"},
+				{Content: "# A Markdown file", Source: server.URL + "/markdown", Metadata: common.Metadata{ContentType: "text/plain"}},
 			},
 			wantErr: false,
 		},
@@ -318,8 +309,8 @@ func TestProcess(t *testing.T) {
 				urls: []string{server.URL + "/text", server.URL + "/html"},
 			},
 			want: []common.Chunk{
-				{Content: "An HTML file.", Source: server.URL + "/html"},
-				{Content: "A plain text file.", Source: server.URL + "/text"},
+				{Content: "An HTML file.", Source: server.URL + "/html", Metadata: common.Metadata{ContentType: "text/html"}},
+				{Content: "A plain text file.", Source: server.URL + "/text", Metadata: common.Metadata{ContentType: "text/plain"}},
 			},
 			wantErr:          false,
 			orderIndependent: true,
@@ -355,7 +346,7 @@ func TestProcess(t *testing.T) {
 				urls: []string{server.URL + "/text"},
 				ctxs: []context.Context{context.Background(), context.Background()},
 			},
-			want:    []common.Chunk{{Content: "A plain text file.", Source: server.URL + "/text"}},
+			want:    []common.Chunk{{Content: "A plain text file.", Source: server.URL + "/text", Metadata: common.Metadata{ContentType: "text/plain"}}},
 			wantErr: false,
 		},
 		{
@@ -372,7 +363,7 @@ func TestProcess(t *testing.T) {
 				urls: []string{server.URL + "/disallowed"},
 			},
 			want: []common.Chunk{
-				{Content: "This page is disallowed by robots.txt", Source: server.URL + "/disallowed"},
+				{Content: "This page is disallowed by robots.txt", Source: server.URL + "/disallowed", Metadata: common.Metadata{ContentType: "text/plain"}},
 			},
 			wantErr:         false,
 			ignoreRobotsTxt: true,
@@ -392,8 +383,8 @@ func TestProcess(t *testing.T) {
 				urls: []string{server.URL + "/text", server.URL + "/html"},
 			},
 			want: []common.Chunk{
-				{Content: "A plain text file.", Source: server.URL + "/text"},
-				{Content: "An HTML file.", Source: server.URL + "/html"},
+				{Content: "A plain text file.", Source: server.URL + "/text", Metadata: common.Metadata{ContentType: "text/plain"}},
+				{Content: "An HTML file.", Source: server.URL + "/html", Metadata: common.Metadata{ContentType: "text/html"}},
 			},
 			wantErr:          false,
 			orderIndependent: true,
@@ -442,6 +433,7 @@ func TestProcess(t *testing.T) {
 			}
 
 			got, err := chew.Process(ctx, tt.args.urls)
+			got = stripMetadata(t, got)
 
 			if tt.wantErr {
 				if err == nil {
@@ -581,3 +573,212 @@ func TestRespectCrawlDelay(t *testing.T) {
 		})
 	}
 }
+
+func TestRespectCrawlDelay_Concurrent(t *testing.T) {
+	c := New(Config{})
+	delay := 100 * time.Millisecond
+
+	var wg sync.WaitGroup
+	start := time.Now()
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.respectCrawlDelay(context.Background(), "https://example.com/page", delay); err != nil {
+				t.Errorf("respectCrawlDelay() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the first request goes right away and every other one waits for the one before it
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("respectCrawlDelay() let 3 concurrent requests through in %v, want at least %v", elapsed, 2*delay)
+	}
+}
+
+func TestProcessStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("A plain text file."))
+		case "/html":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><body><p>First.</p><p>Second.</p></body></html>"))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("not really a png"))
+		}
+	}))
+	defer server.Close()
+
+	chew := New(Config{
+		IgnoreRobotsTxt: true,
+		RateLimit:       time.Millisecond,
+		RateBurst:       10,
+	})
+
+	urls := []string{server.URL + "/text", server.URL + "/image", server.URL + "/html"}
+
+	chunks := make(map[string][]string)
+	errs := make(map[string]error)
+	for res := range chew.ProcessStream(context.Background(), urls) {
+		if res.Err != nil {
+			errs[res.URL] = res.Err
+			continue
+		}
+		if res.Chunk.Source != res.URL {
+			t.Errorf("ProcessStream() chunk source = %v, want %v", res.Chunk.Source, res.URL)
+		}
+		chunks[res.URL] = append(chunks[res.URL], res.Chunk.Content)
+	}
+
+	want := map[string][]string{
+		server.URL + "/text": {"A plain text file."},
+		server.URL + "/html": {"First.", "Second."},
+	}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("ProcessStream() chunks = %v, want %v", chunks, want)
+	}
+	if len(errs) != 1 || errs[server.URL+"/image"] == nil {
+		t.Errorf("ProcessStream() errors = %v, want a single error for /image", errs)
+	}
+}
+
+func TestProcessStream_ContextCancelled(t *testing.T) {
+	chew := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stream := chew.ProcessStream(ctx, []string{"https://example.com/a", "https://example.com/b"})
+
+	select {
+	case _, ok := <-stream:
+		for ok {
+			_, ok = <-stream
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ProcessStream() did not close the channel after the context was cancelled")
+	}
+}
+
+func Test_processContent_Chunking(t *testing.T) {
+	chew := New(Config{
+		Chunking: ChunkingOptions{Strategy: ChunkByParagraph, MaxChars: 40},
+	})
+
+	html := `<html><body>
+		<h1>Intro</h1><p>Short paragraph.</p><p>Another short one.</p>
+		<h1>Details</h1><p>Details go here.</p>
+	</body></html>`
+
+	got, err := chew.processContent(strings.NewReader(html), "text/html", "https://example.com")
+	if err != nil {
+		t.Fatalf("processContent() error = %v", err)
+	}
+
+	want := []string{"Intro\n\nShort paragraph.", "Another short one.", "Details\n\nDetails go here."}
+	var contents []string
+	for _, chunk := range got {
+		contents = append(contents, chunk.Content)
+	}
+	if !reflect.DeepEqual(contents, want) {
+		t.Errorf("processContent() = %q, want %q", contents, want)
+	}
+}
+
+func Test_getRobotsTxtInfo(t *testing.T) {
+	userAgents := make(chan string, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		userAgents <- r.UserAgent()
+		w.Write([]byte("User-agent: chewbot\nCrawl-delay: 3\nDisallow: /private\n\nUser-agent: *\nDisallow: /\n"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		config    Config
+		url       string
+		wantAllow bool
+		wantDelay time.Duration
+	}{
+		{
+			name:      "group crawl delay",
+			config:    Config{UserAgent: "chewbot/1.0", CrawlDelay: time.Second},
+			url:       server.URL + "/public",
+			wantAllow: true,
+			wantDelay: 3 * time.Second,
+		},
+		{
+			name:      "config crawl delay is longer",
+			config:    Config{UserAgent: "chewbot/1.0", CrawlDelay: 5 * time.Second},
+			url:       server.URL + "/public",
+			wantAllow: true,
+			wantDelay: 5 * time.Second,
+		},
+		{
+			name:      "disallowed for the agent",
+			config:    Config{UserAgent: "chewbot/1.0"},
+			url:       server.URL + "/private/page",
+			wantAllow: false,
+			wantDelay: 3 * time.Second,
+		},
+		{
+			name:      "other agents",
+			config:    Config{UserAgent: "otherbot"},
+			url:       server.URL + "/public",
+			wantAllow: false,
+			wantDelay: 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.config)
+
+			allowed, delay, err := c.getRobotsTxtInfo(context.Background(), tt.url)
+			if err != nil {
+				t.Fatalf("getRobotsTxtInfo() error = %v", err)
+			}
+			if allowed != tt.wantAllow || delay != tt.wantDelay {
+				t.Errorf("getRobotsTxtInfo() = %v, %v, want %v, %v", allowed, delay, tt.wantAllow, tt.wantDelay)
+			}
+			if ua := <-userAgents; ua != tt.config.UserAgent {
+				t.Errorf("robots.txt was fetched with User-Agent %q, want %q", ua, tt.config.UserAgent)
+			}
+		})
+	}
+}
+
+func Test_getRobotsTxtInfo_CacheTTL(t *testing.T) {
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		w.Write([]byte("User-agent: *\nAllow: /\n"))
+	}))
+	defer server.Close()
+
+	c := New(Config{RobotsCacheTTL: 50 * time.Millisecond})
+
+	for i := 0; i < 3; i++ {
+		if _, _, err := c.getRobotsTxtInfo(context.Background(), server.URL+"/page"); err != nil {
+			t.Fatalf("getRobotsTxtInfo() error = %v", err)
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, _, err := c.getRobotsTxtInfo(context.Background(), server.URL+"/page"); err != nil {
+		t.Fatalf("getRobotsTxtInfo() error = %v", err)
+	}
+	if got := fetches.Load(); got != 2 {
+		t.Errorf("robots.txt fetched %d times after the TTL, want 2", got)
+	}
+}
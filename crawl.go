@@ -0,0 +1,274 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+/*
+CrawlOptions controls which links are followed by Crawl. A link is only followed if it passes every
+filter that's set.
+
+Fields:
+  - MaxDepth: How many links away from a seed pages are fetched, 0 means no limit (e.g., 3)
+  - MaxPages: Maximum number of pages fetched, seeds included, 0 means no limit (e.g., 500)
+  - AllowedDomains: Domains that are crawled along with their subdomains, defaults to the hosts of the seeds (e.g., []string{"example.com"})
+  - PathPrefixes: Path prefixes that are crawled, any path if empty (e.g., []string{"/docs/", "/blog/"})
+  - Patterns: Regular expressions matched against the full URL, a link has to match at least one if set (e.g., []*regexp.Regexp{regexp.MustCompile(`/v2/`)})
+  - ExcludePatterns: Regular expressions matched against the full URL, links matching any of them are skipped (e.g., []*regexp.Regexp{regexp.MustCompile(`\.(png|jpg)$`)})
+*/
+type CrawlOptions struct {
+	MaxDepth        int
+	MaxPages        int
+	AllowedDomains  []string
+	PathPrefixes    []string
+	Patterns        []*regexp.Regexp
+	ExcludePatterns []*regexp.Regexp
+}
+
+/*
+Crawl processes the seeds and follows the links found on the HTML pages it processes, as allowed
+by opts. Links are resolved against the page they were found on and normalized before being
+deduplicated, so every page is fetched at most once.
+
+Every page goes through the same politeness checks as Process, i.e. robots.txt, crawl delays, the
+rate limiter and the concurrency limits, and the result is reported the same way as ProcessBatch.
+Pages are keyed by their normalized URL, except for the seeds which are keyed exactly as passed in.
+
+Unless Config.RobotsTags is RobotsTagsIgnore, links marked rel="nofollow" and the links of pages
+marked nofollow aren't followed, while the links of pages marked noindex still are.
+
+The error returned is only non-nil if the context was cancelled, in which case the result holds
+whatever was processed before that.
+
+This function is safe for concurrent use.
+
+Usage:
+
+	result, err := c.Crawl(ctx, []string{"https://example.com/docs/"}, chew.CrawlOptions{
+	    MaxDepth:     3,
+	    MaxPages:     500,
+	    PathPrefixes: []string{"/docs/"},
+	})
+*/
+func (c *Chew) Crawl(ctx context.Context, seeds []string, opts CrawlOptions) (*BatchResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type job struct {
+		url   string
+		depth int
+	}
+
+	var (
+		result = &BatchResult{
+			Chunks:  make(map[string][]common.Chunk),
+			Reports: make(map[string]*SourceReport),
+		}
+		filter  = newCrawlFilter(seeds, opts)
+		seen    = make(map[string]bool)
+		queue   []job
+		results = make(chan struct {
+			sourceResult
+			depth int
+		})
+		inFlight, started int
+	)
+
+	for _, seed := range seeds {
+		key := normalizeURL(seed)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		queue = append(queue, job{url: seed})
+	}
+
+	workers := c.config.MaxConcurrency
+	if workers <= 0 {
+		workers = defaultMaxConcurrency
+	}
+
+	for {
+		for ctx.Err() == nil && inFlight < workers && len(queue) > 0 && (opts.MaxPages <= 0 || started < opts.MaxPages) {
+			j := queue[0]
+			queue = queue[1:]
+			inFlight++
+			started++
+
+			go func() {
+				sr := c.runOne(ctx, j.url, fetchInfo{collectLinks: true})
+				results <- struct {
+					sourceResult
+					depth int
+				}{sr, j.depth}
+			}()
+		}
+
+		if inFlight == 0 {
+			break
+		}
+
+		res := <-results
+		inFlight--
+
+		result.Reports[res.url] = &SourceReport{
+			Err:        res.err,
+			Attempts:   res.info.attempts,
+			StatusCode: res.info.statusCode,
+			Duration:   res.duration,
+			Depth:      res.depth,
+		}
+		// a page that asks not to be indexed may still be followed
+		if res.err != nil && !errors.Is(res.err, ErrNoIndex) {
+			continue
+		}
+		if res.err == nil || len(res.chunks) > 0 {
+			result.Chunks[res.url] = res.chunks
+		}
+
+		if opts.MaxDepth > 0 && res.depth >= opts.MaxDepth {
+			continue
+		}
+		for _, link := range res.info.links {
+			// the normalized URL only tells pages apart, the link is fetched as the page has it
+			key := normalizeURL(link)
+			if seen[key] || !filter.allows(key) {
+				continue
+			}
+			seen[key] = true
+			queue = append(queue, job{url: stripFragment(link), depth: res.depth + 1})
+		}
+	}
+
+	return result, ctx.Err()
+}
+
+type crawlFilter struct {
+	domains  []string
+	prefixes []string
+	patterns []*regexp.Regexp
+	exclude  []*regexp.Regexp
+}
+
+func newCrawlFilter(seeds []string, opts CrawlOptions) crawlFilter {
+	f := crawlFilter{
+		prefixes: opts.PathPrefixes,
+		patterns: opts.Patterns,
+		exclude:  opts.ExcludePatterns,
+	}
+
+	domains := opts.AllowedDomains
+	if len(domains) == 0 {
+		for _, seed := range seeds {
+			if u, err := url.Parse(seed); err == nil && u.Hostname() != "" {
+				domains = append(domains, u.Hostname())
+			}
+		}
+	}
+	for _, d := range domains {
+		if d = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), ".")); d != "" {
+			f.domains = append(f.domains, d)
+		}
+	}
+
+	return f
+}
+
+func (f crawlFilter) allows(link string) bool {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	allowed := false
+	for _, d := range f.domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+
+	if len(f.prefixes) > 0 {
+		allowed = false
+		for _, prefix := range f.prefixes {
+			if strings.HasPrefix(u.Path, prefix) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	if len(f.patterns) > 0 {
+		allowed = false
+		for _, re := range f.patterns {
+			if re.MatchString(link) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	for _, re := range f.exclude {
+		if re.MatchString(link) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+normalizeURL puts a URL in a canonical form so the same page isn't crawled twice, the scheme and
+host are lowercased, default ports and fragments are dropped and query parameters are sorted. The
+parameters are sorted as they're written, without decoding them, so that URLs only differing in
+how their query is written are still told apart. URLs that can't be parsed are returned as is.
+*/
+func normalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		slices.Sort(params)
+		u.RawQuery = strings.Join(params, "&")
+	}
+
+	return u.String()
+}
+
+// stripFragment removes the fragment of a URL, which only matters to the browser showing the page.
+func stripFragment(rawURL string) string {
+	if i := strings.IndexByte(rawURL, '#'); i >= 0 {
+		return rawURL[:i]
+	}
+	return rawURL
+}
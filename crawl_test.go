@@ -0,0 +1,165 @@
+package chew
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newCrawlServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	pages := map[string]string{
+		"/":          `<a href="/docs/a">A</a> <a href="docs/b#top">B</a> <a href="/blog/post">Post</a> <a href="https://other.invalid/">Other</a>`,
+		"/docs/a":    `<p>Page A</p> <a href="/docs/c?y=2&x=1">C</a> <a href="/">Home</a>`,
+		"/docs/b":    `<p>Page B</p> <a href="/docs/c?x=1&y=2">C again</a> <a href="/docs/file.pdf">PDF</a>`,
+		"/docs/c":    `<p>Page C</p> <a href="/docs/d">D</a>`,
+		"/docs/d":    `<p>Page D</p>`,
+		"/blog/post": `<p>Post</p>`,
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><body>%s</body></html>", page)
+	}))
+}
+
+func TestCrawl(t *testing.T) {
+	server := newCrawlServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		opts CrawlOptions
+		want map[string]int
+	}{
+		{
+			name: "same site",
+			opts: CrawlOptions{ExcludePatterns: []*regexp.Regexp{regexp.MustCompile(`\.pdf$`)}},
+			want: map[string]int{
+				"/": 0, "/docs/a": 1, "/docs/b": 1, "/blog/post": 1, "/docs/c?x=1&y=2": 2, "/docs/d": 3,
+			},
+		},
+		{
+			name: "max depth and path prefix",
+			opts: CrawlOptions{MaxDepth: 2, PathPrefixes: []string{"/docs/"}, Patterns: []*regexp.Regexp{regexp.MustCompile(`/docs/[a-c]`)}},
+			want: map[string]int{
+				"/": 0, "/docs/a": 1, "/docs/b": 1, "/docs/c?x=1&y=2": 2,
+			},
+		},
+		{
+			name: "max pages",
+			opts: CrawlOptions{MaxPages: 1},
+			want: map[string]int{"/": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 10})
+
+			result, err := c.Crawl(context.Background(), []string{server.URL + "/", server.URL}, tt.opts)
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+
+			got := make(map[string]int)
+			for url, report := range result.Reports {
+				if report.Err != nil {
+					t.Errorf("Crawl() %s failed: %v", url, report.Err)
+				}
+				// which of the links to the same page is fetched depends on which page comes back first
+				got[normalizeURL(url)[len(server.URL):]] = report.Depth
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				var keys []string
+				for k := range got {
+					keys = append(keys, k)
+				}
+				sort.Strings(keys)
+				t.Errorf("Crawl() fetched %v = %v, want %v", keys, got, tt.want)
+			}
+
+			if len(result.Chunks[server.URL+"/"]) != 0 {
+				t.Errorf("Crawl() chunks = %v, the home page has no text", result.Chunks[server.URL+"/"])
+			}
+			if len(result.Chunks[server.URL+"/docs/a"]) == 0 && tt.want["/docs/a"] == 1 {
+				t.Errorf("Crawl() has no chunks for /docs/a")
+			}
+		})
+	}
+}
+
+func TestCrawl_LinksFetchedAsWritten(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		queries []string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, `<html><body><a href="/search?flag&amp;b=2;c=3#results">Search</a> <a href="/search?b=2;c=3&amp;flag">Again</a></body></html>`)
+			return
+		}
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+		fmt.Fprint(w, "<html><body><p>Results</p></body></html>")
+	}))
+	defer server.Close()
+
+	c := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 10})
+	result, err := c.Crawl(context.Background(), []string{server.URL + "/"}, CrawlOptions{})
+	if err != nil {
+		t.Fatalf("Crawl() error = %v", err)
+	}
+
+	if want := []string{"flag&b=2;c=3"}; !reflect.DeepEqual(queries, want) {
+		t.Errorf("Crawl() requested queries %q, want %q", queries, want)
+	}
+	if _, ok := result.Reports[server.URL+"/search?flag&b=2;c=3"]; !ok {
+		t.Errorf("Crawl() reports = %v, want the link as written", result.Reports)
+	}
+}
+
+func TestCrawl_ContextCancelled(t *testing.T) {
+	server := newCrawlServer(t)
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 10})
+	if _, err := c.Crawl(ctx, []string{server.URL}, CrawlOptions{}); err != context.Canceled {
+		t.Errorf("Crawl() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func Test_normalizeURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"HTTPS://Example.COM:443/a#frag", "https://example.com/a"},
+		{"http://example.com", "http://example.com/"},
+		{"http://example.com:8080/?b=2&a=1", "http://example.com:8080/?a=1&b=2"},
+		{"http://example.com/?flag&b=2;c=3&a=%7e", "http://example.com/?a=%7e&b=2;c=3&flag"},
+		{"file:///tmp/a.txt", "file:///tmp/a.txt"},
+	}
+	for _, tt := range tests {
+		if got := normalizeURL(tt.url); got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
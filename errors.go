@@ -0,0 +1,107 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/*
+Errors returned while processing a source wrap one of these, so they can be told apart with errors.Is
+rather than by their message.
+
+Usage:
+
+	chunks, err := c.Process(ctx, urls)
+	if errors.Is(err, chew.ErrDisallowedByRobots) {
+	    // the site doesn't want to be crawled
+	}
+*/
+var (
+	// ErrDisallowedByRobots is returned for URLs the robots.txt of their site disallows for Config.UserAgent.
+	ErrDisallowedByRobots = errors.New("disallowed by robots.txt")
+	// ErrUnsupportedContentType is returned for content no registered processor handles.
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	// ErrRateLimited is returned when the rate limiter can't grant a request, and matches an HTTPError with status 429.
+	ErrRateLimited = errors.New("rate limited")
+	// ErrFileTooLarge is returned for files larger than Config.Files.MaxFileSize.
+	ErrFileTooLarge = errors.New("file too large")
+	// ErrArchiveLimit is returned for archives going over one of the limits of Config.Archives.
+	ErrArchiveLimit = errors.New("archive limit exceeded")
+	// ErrPartialContent is returned along with the chunks of what could be processed when part of the content couldn't, e.g. an entry of an archive.
+	ErrPartialContent = errors.New("content partly processed")
+	// ErrNoIndex is returned for content marked noindex when Config.RobotsTags is RobotsTagsSkip.
+	ErrNoIndex = errors.New("content is marked noindex")
+)
+
+/*
+rateLimitError wraps an error waiting on a rate limiter in ErrRateLimited, unless it's down to ctx being
+done, in which case the error of ctx is returned as it is so cancelled jobs aren't taken for rate limited ones.
+*/
+func rateLimitError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return fmt.Errorf("%w: %w", ErrRateLimited, err)
+}
+
+// maxErrorBody is how much of the body of an error response is kept in an HTTPError.
+const maxErrorBody = 512
+
+/*
+HTTPError is returned when a URL is answered with a status outside of the 2xx range, the body of
+the response isn't processed.
+
+Fields:
+  - StatusCode: The status code of the response (e.g., 404)
+  - URL: The URL that was requested
+  - Body: The start of the body of the response, useful to tell why a request was refused
+  - RetryAfter: How long the server asked to wait before trying again, from its Retry-After header (e.g., 30 * time.Second)
+
+Usage:
+
+	var httpErr *chew.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusNotFound {
+	    // the page is gone
+	}
+*/
+type HTTPError struct {
+	StatusCode int
+	URL        string
+	Body       string
+	RetryAfter time.Duration
+}
+
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("unexpected status %d %s for %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+	if e.Body != "" {
+		msg += ": " + e.Body
+	}
+	return msg
+}
+
+// Is makes a 429 Too Many Requests match ErrRateLimited.
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrRateLimited && e.StatusCode == http.StatusTooManyRequests
+}
+
+// newHTTPError reads the start of the body of resp into an HTTPError.
+func newHTTPError(resp *http.Response, url string) *HTTPError {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	// the limit may have cut a character in half
+	for len(body) > 0 && !utf8.Valid(body) {
+		body = body[:len(body)-1]
+	}
+
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		URL:        url,
+		Body:       strings.Join(strings.Fields(string(body)), " "),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
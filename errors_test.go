@@ -0,0 +1,110 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcess_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private"))
+		case "/missing":
+			w.Header().Set("Content-Type", "text/html")
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte("<html><body><h1>Not   Found</h1>\n" + strings.Repeat("x", 2*maxErrorBody) + "</body></html>"))
+		case "/busy":
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/binary":
+			w.Header().Set("Content-Type", "application/x-unknown")
+			w.Write([]byte{0x00, 0x01, 0x02})
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantIs     error
+		wantStatus int
+	}{
+		{name: "not found", path: "/missing", wantStatus: http.StatusNotFound},
+		{name: "too many requests", path: "/busy", wantIs: ErrRateLimited, wantStatus: http.StatusTooManyRequests},
+		{name: "disallowed", path: "/private", wantIs: ErrDisallowedByRobots},
+		{name: "unsupported", path: "/binary", wantIs: ErrUnsupportedContentType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{RateLimit: time.Millisecond, RateBurst: 10})
+
+			_, err := c.Process(context.Background(), []string{server.URL + tt.path})
+			if err == nil {
+				t.Fatal("Process() error = nil")
+			}
+			if tt.wantIs != nil && !errors.Is(err, tt.wantIs) {
+				t.Errorf("Process() error = %v, want %v", err, tt.wantIs)
+			}
+
+			var httpErr *HTTPError
+			if !errors.As(err, &httpErr) {
+				if tt.wantStatus != 0 {
+					t.Fatalf("Process() error = %v, want an HTTPError", err)
+				}
+				return
+			}
+			if httpErr.StatusCode != tt.wantStatus || httpErr.URL != server.URL+tt.path {
+				t.Errorf("HTTPError = %+v, want status %d for %s", httpErr, tt.wantStatus, server.URL+tt.path)
+			}
+			if len(httpErr.Body) > maxErrorBody {
+				t.Errorf("HTTPError.Body is %d bytes, want at most %d", len(httpErr.Body), maxErrorBody)
+			}
+			if tt.path == "/missing" && !strings.HasPrefix(httpErr.Body, "<html><body><h1>Not Found</h1> xxx") {
+				t.Errorf("HTTPError.Body = %q", httpErr.Body)
+			}
+		})
+	}
+}
+
+func TestProcessSource_RateLimiterErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		cancel    bool
+		wantIs    error
+		wantNotIs error
+	}{
+		{name: "limiter", wantIs: ErrRateLimited},
+		{name: "cancelled", cancel: true, wantIs: context.Canceled, wantNotIs: ErrRateLimited},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			waitErr := errors.New("would exceed context deadline")
+			if tt.cancel {
+				cancel()
+				waitErr = ctx.Err()
+			}
+
+			c := New(Config{IgnoreRobotsTxt: true})
+			c.SetRateLimiter(&mockRateLimiter{waitErr: waitErr})
+
+			_, err := c.processSource(ctx, "https://example.com", &fetchInfo{})
+			if !errors.Is(err, tt.wantIs) {
+				t.Errorf("processSource() error = %v, want %v", err, tt.wantIs)
+			}
+			if tt.wantNotIs != nil && errors.Is(err, tt.wantNotIs) {
+				t.Errorf("processSource() error = %v, want it not to be %v", err, tt.wantNotIs)
+			}
+		})
+	}
+}
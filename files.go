@@ -0,0 +1,82 @@
+package chew
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+	"github.com/mmatongo/chew/v1/internal/fileset"
+)
+
+/*
+FileOptions controls how file:// sources pointing at a directory or holding a glob pattern, such as
+file:///data/reports/2024-*.csv, are expanded into the files they match. Every file found is processed
+and reported on its own, keyed by its own file:// URL. Patterns use slash separated paths where a **
+segment matches any number of directories, patterns without a slash are matched against the name of
+the file alone.
+
+Fields:
+  - Include: Patterns a file has to match one of, any file if empty (e.g., []string{"*.pdf", "*.md"})
+  - Exclude: Patterns of files and directories that are skipped (e.g., []string{"node_modules", "drafts/**"})
+  - FollowSymlinks: Whether symbolic links are followed, they're skipped otherwise (e.g., true)
+  - IncludeHidden: Whether files and directories starting with a dot are included (e.g., false)
+  - MaxFileSize: Files larger than this many bytes fail with ErrFileTooLarge, 0 means no limit (e.g., 50 << 20)
+*/
+type FileOptions = common.FileOptions
+
+/*
+expandSources replaces the file:// sources that are directories or glob patterns with the files they
+match, leaving the other sources as they are. Sources that can't be expanded, and files that are too
+large, are returned as failed results.
+*/
+func (c *Chew) expandSources(urls []string) ([]string, []sourceResult) {
+	var (
+		sources []string
+		failed  []sourceResult
+		seen    = make(map[string]bool)
+	)
+
+	for _, url := range urls {
+		filePath, found := strings.CutPrefix(url, "file://")
+		if !found {
+			sources = append(sources, url)
+			continue
+		}
+
+		var root, pattern string
+		info, err := os.Stat(filePath)
+		switch {
+		case err == nil && info.IsDir():
+			root = filePath
+		case err != nil && fileset.HasMeta(filePath):
+			root, pattern = fileset.Split(filePath)
+		default:
+			sources = append(sources, url)
+			continue
+		}
+
+		files, err := fileset.Expand(root, pattern, c.config.Files)
+		if err != nil {
+			failed = append(failed, sourceResult{url: url, err: fmt.Errorf("expanding %s: %w", url, err)})
+		} else if len(files) == 0 && pattern != "" {
+			failed = append(failed, sourceResult{url: url, err: fmt.Errorf("no files match %s", url)})
+		}
+
+		for _, f := range files {
+			fileURL := "file://" + f.Path
+			if seen[fileURL] {
+				continue
+			}
+			seen[fileURL] = true
+
+			if c.config.Files.MaxFileSize > 0 && f.Size > c.config.Files.MaxFileSize {
+				failed = append(failed, sourceResult{url: fileURL, err: fmt.Errorf("%s: %w", fileURL, ErrFileTooLarge)})
+				continue
+			}
+			sources = append(sources, fileURL)
+		}
+	}
+
+	return sources, failed
+}
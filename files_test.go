@@ -0,0 +1,157 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestProcessBatch_FileExpansion(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"a.txt":          "first",
+		"notes/b.txt":    "second",
+		"notes/c.md":     "# third",
+		"notes/.hidden":  "hidden",
+		"large/big.txt":  "this one is far too large",
+		"empty/.gitkeep": "",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		urls     []string
+		files    FileOptions
+		wantOK   []string
+		wantErrs map[string]error
+	}{
+		{
+			name:   "directory",
+			urls:   []string{"file://" + filepath.Join(root, "notes")},
+			wantOK: []string{"notes/b.txt", "notes/c.md"},
+		},
+		{
+			name:   "glob",
+			urls:   []string{"file://" + root + "/**/*.txt"},
+			files:  FileOptions{Exclude: []string{"large"}},
+			wantOK: []string{"a.txt", "notes/b.txt"},
+		},
+		{
+			name:   "overlapping sources are processed once",
+			urls:   []string{"file://" + filepath.Join(root, "notes"), "file://" + root + "/notes/*.txt"},
+			wantOK: []string{"notes/b.txt", "notes/c.md"},
+		},
+		{
+			name:   "include",
+			urls:   []string{"file://" + root},
+			files:  FileOptions{Include: []string{"*.md"}},
+			wantOK: []string{"notes/c.md"},
+		},
+		{
+			name:     "too large",
+			urls:     []string{"file://" + filepath.Join(root, "large"), "file://" + filepath.Join(root, "a.txt")},
+			files:    FileOptions{MaxFileSize: 10},
+			wantOK:   []string{"a.txt"},
+			wantErrs: map[string]error{"large/big.txt": ErrFileTooLarge},
+		},
+		{
+			name:     "no match",
+			urls:     []string{"file://" + root + "/*.pdf"},
+			wantErrs: map[string]error{"*.pdf": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{Files: tt.files})
+
+			result, err := c.ProcessBatch(context.Background(), tt.urls)
+			if err != nil {
+				t.Fatalf("ProcessBatch() error = %v", err)
+			}
+
+			var ok []string
+			for url, report := range result.Reports {
+				rel, _ := filepath.Rel(root, url[len("file://"):])
+				rel = filepath.ToSlash(rel)
+
+				wantErr, failed := tt.wantErrs[rel]
+				switch {
+				case failed && report.Err == nil:
+					t.Errorf("%s: error = nil, want an error", rel)
+				case failed && wantErr != nil && !errors.Is(report.Err, wantErr):
+					t.Errorf("%s: error = %v, want %v", rel, report.Err, wantErr)
+				case !failed && report.Err != nil:
+					t.Errorf("%s: error = %v", rel, report.Err)
+				case !failed:
+					if len(result.Chunks[url]) == 0 {
+						t.Errorf("%s: no chunks", rel)
+					}
+					ok = append(ok, rel)
+				}
+			}
+			sort.Strings(ok)
+
+			if len(ok) != len(tt.wantOK) {
+				t.Fatalf("processed %v, want %v", ok, tt.wantOK)
+			}
+			for i := range ok {
+				if ok[i] != tt.wantOK[i] {
+					t.Errorf("processed %v, want %v", ok, tt.wantOK)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestProcessURL_FileTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.txt")
+	if err := os.WriteFile(path, []byte("more than ten bytes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(Config{Files: FileOptions{MaxFileSize: 10}})
+	if _, err := c.Process(context.Background(), []string{"file://" + path}); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("Process() error = %v, want ErrFileTooLarge", err)
+	}
+}
+
+func TestProcessBatch_FilesSkipPoliteness(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a.md", "b.md", "c.md"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("# "+name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// neither the rate limiter nor the crawl delay would let three files through in time
+	c := New(Config{CrawlDelay: time.Hour, RateLimit: time.Hour, RateBurst: 1})
+	c.SetRateLimiter(&mockRateLimiter{waitErr: errors.New("rate limit exceeded")})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := c.ProcessBatch(ctx, []string{"file://" + root})
+	if err != nil {
+		t.Fatalf("ProcessBatch() error = %v", err)
+	}
+	for url, report := range result.Reports {
+		if report.Err != nil {
+			t.Errorf("%s: error = %v", url, report.Err)
+		}
+	}
+	if len(result.Chunks) != 3 {
+		t.Errorf("ProcessBatch() processed %d files, want 3", len(result.Chunks))
+	}
+}
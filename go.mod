@@ -46,7 +46,6 @@ require (
 	golang.org/x/oauth2 v0.21.0 // indirect
 	golang.org/x/sync v0.7.0 // indirect
 	golang.org/x/sys v0.22.0 // indirect
-	golang.org/x/text v0.16.0 // indirect
 	google.golang.org/genproto v0.0.0-20240624140628-dc46fd24d27d // indirect
 	google.golang.org/genproto/googleapis/api v0.0.0-20240617180043-68d350f18fd4 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240624140628-dc46fd24d27d // indirect
@@ -57,7 +56,8 @@ require (
 
 require (
 	cloud.google.com/go/storage v1.43.0
-	github.com/andybalholm/cascadia v1.3.2 // indirect
-	golang.org/x/net v0.27.0 // indirect
+	github.com/andybalholm/cascadia v1.3.2
+	golang.org/x/net v0.27.0
+	golang.org/x/text v0.16.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,157 @@
+package chew
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// minThrottleInterval is the interval a host without a rate limit is slowed down to when it first pushes back.
+	minThrottleInterval = time.Second
+	// maxThrottleInterval caps how far a host is slowed down.
+	maxThrottleInterval = time.Minute
+	// recoverySnap is how close to its configured interval a host has to recover before it's considered recovered.
+	recoverySnap = 50 * time.Millisecond
+)
+
+/*
+HostRate is the rate limit applied to a single host, on top of the global one.
+
+Fields:
+  - RateLimit: Minimum interval between requests to the host, 0 means no limit (e.g., 500 * time.Millisecond)
+  - RateBurst: Number of requests that can be made at once before the interval applies, defaults to 1 (e.g., 5)
+
+Usage:
+
+	c := chew.New(chew.Config{
+	    HostRateLimit: time.Second,
+	    HostRateLimits: map[string]chew.HostRate{
+	        "api.example.com": {RateLimit: 5 * time.Second},
+	    },
+	})
+*/
+type HostRate = common.HostRate
+
+/*
+hostLimiters rate limits requests per host. Every host starts at the rate configured for it and is
+slowed down whenever it answers with a 429 or a 503, the interval between requests doubles up to
+maxThrottleInterval. Every other response brings it back a quarter of the way towards the
+configured rate, so a host recovers gradually once it stops pushing back.
+*/
+type hostLimiters struct {
+	defaults HostRate
+	domains  map[string]HostRate
+	mu       sync.Mutex
+	hosts    map[string]*hostLimiter
+}
+
+type hostLimiter struct {
+	limiter  *rate.Limiter
+	rate     HostRate
+	interval time.Duration
+}
+
+func newHostLimiters(config common.Config) *hostLimiters {
+	h := &hostLimiters{
+		defaults: HostRate{RateLimit: config.HostRateLimit, RateBurst: config.HostRateBurst},
+		domains:  make(map[string]HostRate),
+		hosts:    make(map[string]*hostLimiter),
+	}
+	for domain, r := range config.HostRateLimits {
+		if domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), ".")); domain != "" {
+			h.domains[domain] = r
+		}
+	}
+	return h
+}
+
+// rateFor returns the rate configured for host, the most specific domain matching it wins.
+func (h *hostLimiters) rateFor(host string) HostRate {
+	var (
+		best  string
+		r     = h.defaults
+		found bool
+	)
+	for domain, dr := range h.domains {
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			continue
+		}
+		if !found || len(domain) > len(best) {
+			best, r, found = domain, dr, true
+		}
+	}
+	return r
+}
+
+func (h *hostLimiters) get(host string) *hostLimiter {
+	host = strings.ToLower(host)
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	l, ok := h.hosts[host]
+	if !ok {
+		r := h.rateFor(host)
+		l = &hostLimiter{
+			limiter:  rate.NewLimiter(limitFor(r.RateLimit), max(r.RateBurst, 1)),
+			rate:     r,
+			interval: r.RateLimit,
+		}
+		h.hosts[host] = l
+	}
+	return l
+}
+
+// wait blocks until a request can be made to host, requests without a host (i.e. files) aren't limited.
+func (h *hostLimiters) wait(ctx context.Context, host string) error {
+	if h == nil || host == "" {
+		return nil
+	}
+	return h.get(host).limiter.Wait(ctx)
+}
+
+// observe adjusts the rate of host to the status code it answered with.
+func (h *hostLimiters) observe(host string, statusCode int) {
+	if h == nil || host == "" {
+		return
+	}
+	l := h.get(host)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	switch statusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		l.interval = min(max(l.interval*2, minThrottleInterval), max(maxThrottleInterval, l.rate.RateLimit))
+		// a burst would defeat the point of slowing down
+		l.limiter.SetBurst(1)
+	default:
+		if l.interval == l.rate.RateLimit {
+			return
+		}
+		l.interval -= (l.interval - l.rate.RateLimit) / 4
+		if l.interval-l.rate.RateLimit < recoverySnap {
+			l.interval = l.rate.RateLimit
+			l.limiter.SetBurst(max(l.rate.RateBurst, 1))
+		}
+	}
+	l.limiter.SetLimit(limitFor(l.interval))
+}
+
+// limitFor converts an interval between requests into a rate.Limit, 0 means no limit.
+func limitFor(interval time.Duration) rate.Limit {
+	if interval <= 0 {
+		return rate.Inf
+	}
+	return rate.Every(interval)
+}
@@ -0,0 +1,84 @@
+package chew
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func Test_hostLimiters_rateFor(t *testing.T) {
+	h := newHostLimiters(Config{
+		HostRateLimit: time.Second,
+		HostRateLimits: map[string]HostRate{
+			"example.com":      {RateLimit: 2 * time.Second},
+			".api.example.com": {RateLimit: 3 * time.Second, RateBurst: 5},
+		},
+	})
+
+	tests := []struct {
+		host string
+		want HostRate
+	}{
+		{host: "other.com", want: HostRate{RateLimit: time.Second}},
+		{host: "example.com", want: HostRate{RateLimit: 2 * time.Second}},
+		{host: "www.example.com", want: HostRate{RateLimit: 2 * time.Second}},
+		{host: "v1.api.example.com", want: HostRate{RateLimit: 3 * time.Second, RateBurst: 5}},
+		{host: "notexample.com", want: HostRate{RateLimit: time.Second}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			if got := h.rateFor(tt.host); got != tt.want {
+				t.Errorf("rateFor(%q) = %+v, want %+v", tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_hostLimiters_observe(t *testing.T) {
+	h := newHostLimiters(Config{HostRateLimits: map[string]HostRate{"slow.com": {RateLimit: 2 * time.Second}}})
+
+	tests := []struct {
+		name   string
+		host   string
+		status []int
+		want   time.Duration
+	}{
+		{name: "unlimited host is throttled", host: "fast.com:8080", status: []int{429}, want: minThrottleInterval},
+		{name: "backs off further", host: "fast.com:8080", status: []int{503, 429}, want: 4 * minThrottleInterval},
+		{name: "recovers gradually", host: "fast.com:8080", status: []int{200}, want: 3 * minThrottleInterval},
+		{name: "recovers fully", host: "fast.com:8080", status: []int{200, 200, 200, 200, 200, 200, 200, 200, 200, 200, 200, 200, 200, 200, 200}, want: 0},
+		{name: "limited host", host: "slow.com", status: []int{429}, want: 4 * time.Second},
+		{name: "capped", host: "slow.com", status: []int{429, 429, 429, 429, 429, 429, 429, 429}, want: maxThrottleInterval},
+		{name: "other errors don't throttle", host: "other.com", status: []int{500, 404}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, status := range tt.status {
+				h.observe(tt.host, status)
+			}
+			if got := h.get(tt.host).interval; got != tt.want {
+				t.Errorf("interval = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcess_HostRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	c := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 10, HostRateLimit: 50 * time.Millisecond})
+
+	start := time.Now()
+	if _, err := c.Process(context.Background(), []string{server.URL + "/a", server.URL + "/b", server.URL + "/c"}); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Process() took %v, want at least 100ms between three requests to the same host", elapsed)
+	}
+}
@@ -0,0 +1,42 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+func TestDisk(t *testing.T) {
+	ctx := context.Background()
+
+	d, err := NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := d.Get(ctx, "missing"); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("Get() of a missing key error = %v, want ErrCacheMiss", err)
+	}
+
+	for _, value := range []string{"first", "second"} {
+		if err := d.Put(ctx, "key", []byte(value)); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+		got, err := d.Get(ctx, "key")
+		if err != nil || string(got) != value {
+			t.Errorf("Get() = %q, %v, want %q", got, err, value)
+		}
+	}
+
+	if err := d.Delete(ctx, "key"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := d.Get(ctx, "key"); !errors.Is(err, common.ErrCacheMiss) {
+		t.Errorf("Get() after Delete() error = %v, want ErrCacheMiss", err)
+	}
+	if err := d.Delete(ctx, "key"); err != nil {
+		t.Errorf("Delete() of a missing key error = %v", err)
+	}
+}
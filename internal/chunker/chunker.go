@@ -0,0 +1,615 @@
+package chunker
+
+import (
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+const (
+	paragraphSep = "\n\n"
+	lineSep      = "\n"
+	wordSep      = " "
+)
+
+// unit is the smallest piece of text the chunker moves around, sep is what joins it to the unit before it.
+type unit struct {
+	text   string
+	sep    string
+	source string
+	meta   common.Metadata
+}
+
+/*
+Split regroups the chunks produced by a processor according to opts. Chunks are never merged across
+structural boundaries, i.e. different sources, pages, chapters or sections, but within one they are split into
+paragraphs or sentences and packed back together so that each chunk fits within the size limit.
+
+Anything that's still too big after splitting on the strategy's boundaries is split recursively on
+lines, sentences, words and finally characters, and joined back with what it was split on. Fenced code
+blocks and tables are kept whole when they fit, and otherwise split on lines with their fences or
+header rows repeated in every chunk, or kept once at the start when they're too long to repeat.
+*/
+func Split(chunks []common.Chunk, opts common.ChunkingOptions, tokenizer common.Tokenizer) []common.Chunk {
+	if opts.Strategy == common.ChunkNone || len(chunks) == 0 {
+		return chunks
+	}
+
+	b := newBudget(opts, tokenizer)
+
+	var out []common.Chunk
+	for _, group := range groupByBoundary(chunks) {
+		var units []unit
+		for _, chunk := range group {
+			units = append(units, splitChunk(chunk, opts.Strategy, b)...)
+		}
+		out = append(out, pack(units, b)...)
+	}
+
+	renumber(out)
+	return out
+}
+
+/*
+budget decides whether a piece of text is small enough to be a chunk. When both limits are set
+a chunk has to satisfy both of them, the overlap is counted in tokens if there's a token limit
+and in characters otherwise.
+*/
+type budget struct {
+	maxChars  int
+	maxTokens int
+	overlap   int
+	tokenizer common.Tokenizer
+}
+
+func newBudget(opts common.ChunkingOptions, tokenizer common.Tokenizer) budget {
+	b := budget{
+		maxChars:  opts.MaxChars,
+		maxTokens: opts.MaxTokens,
+		overlap:   opts.Overlap,
+		tokenizer: tokenizer,
+	}
+	if b.tokenizer == nil {
+		b.maxTokens = 0
+	}
+
+	if limit := b.overlapLimit(); limit > 0 && b.overlap >= limit {
+		b.overlap = limit / 2
+	}
+	return b
+}
+
+func (b budget) limited() bool {
+	return b.maxChars > 0 || b.maxTokens > 0
+}
+
+func (b budget) fits(text string) bool {
+	return b.within(b.length(text))
+}
+
+/*
+length is the size of some text in each of the units a limit can be set in. The lengths of texts
+are added up rather than measured again once they're joined, which is exact for characters and
+close enough for tokens.
+*/
+type length struct {
+	chars  int
+	tokens int
+}
+
+func (l length) plus(o length) length {
+	return length{chars: l.chars + o.chars, tokens: l.tokens + o.tokens}
+}
+
+func (b budget) length(text string) length {
+	l := length{chars: utf8.RuneCountInString(text)}
+	if b.maxTokens > 0 {
+		l.tokens = b.tokenizer.Count(text)
+	}
+	return l
+}
+
+func (b budget) within(l length) bool {
+	if b.maxChars > 0 && l.chars > b.maxChars {
+		return false
+	}
+	if b.maxTokens > 0 && l.tokens > b.maxTokens {
+		return false
+	}
+	return true
+}
+
+// size measures a length in the same unit as the overlap.
+func (b budget) size(l length) int {
+	if b.maxTokens > 0 {
+		return l.tokens
+	}
+	return l.chars
+}
+
+func (b budget) overlapLimit() int {
+	if b.maxTokens > 0 {
+		return b.maxTokens
+	}
+	return b.maxChars
+}
+
+// groupByBoundary groups consecutive chunks that share the same source, page, chapter and section.
+func groupByBoundary(chunks []common.Chunk) [][]common.Chunk {
+	var (
+		groups [][]common.Chunk
+		last   string
+	)
+	for i, chunk := range chunks {
+		key := boundaryKey(chunk)
+		if i == 0 || key != last {
+			groups = append(groups, nil)
+		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], chunk)
+		last = key
+	}
+	return groups
+}
+
+func boundaryKey(chunk common.Chunk) string {
+	return chunk.Source + "\x00" + strconv.Itoa(chunk.Metadata.Page) + "\x00" + strconv.Itoa(chunk.Metadata.Chapter) + "\x00" +
+		strings.Join(chunk.Metadata.Section, "\x00")
+}
+
+// splitChunk breaks a chunk into units along the boundaries of the strategy.
+func splitChunk(chunk common.Chunk, strategy common.ChunkStrategy, b budget) []unit {
+	var units []unit
+
+	add := func(text, sep string) {
+		for i, p := range fit(text, b) {
+			if i > 0 {
+				sep = p.sep
+			}
+			units = append(units, unit{text: p.text, sep: sep, source: chunk.Source, meta: chunk.Metadata})
+		}
+	}
+
+	switch strategy {
+	case common.ChunkBySentence:
+		for _, paragraph := range splitParagraphs(chunk.Content) {
+			// code and tables have no sentences to speak of
+			if _, _, _, ok := markdownBlock(paragraph); ok {
+				add(paragraph, paragraphSep)
+				continue
+			}
+			for i, sentence := range splitSentences(paragraph) {
+				sep := wordSep
+				if i == 0 {
+					sep = paragraphSep
+				}
+				add(sentence, sep)
+			}
+		}
+	case common.ChunkRecursive:
+		if text := strings.TrimSpace(chunk.Content); text != "" {
+			add(text, paragraphSep)
+		}
+	default:
+		for _, paragraph := range splitParagraphs(chunk.Content) {
+			add(paragraph, paragraphSep)
+		}
+	}
+
+	return units
+}
+
+// piece is a part of some text that fits within the limit, sep is what joins it to the piece before it.
+type piece struct {
+	text string
+	sep  string
+}
+
+// splitter splits text on one kind of boundary, sep is what the parts are joined with again.
+type splitter struct {
+	split func(string) []string
+	sep   string
+}
+
+var splitters = []splitter{
+	{split: splitParagraphs, sep: paragraphSep},
+	{split: splitLines, sep: lineSep},
+	{split: splitSentences, sep: wordSep},
+	{split: strings.Fields, sep: wordSep},
+}
+
+/*
+fit recursively splits text until every piece is within limit, trying the coarsest
+boundary first so that as much structure as possible is kept together. The pieces keep
+the separator they were split on, so lines stay lines when they're packed back together.
+*/
+func fit(text string, b budget) []piece {
+	if b.fits(text) {
+		return []piece{{text: text}}
+	}
+
+	if head, body, tail, ok := markdownBlock(text); ok {
+		return splitBlock(head, body, tail, b)
+	}
+
+	return splitText(text, b)
+}
+
+// splitText splits text on the coarsest boundary it has and packs the parts back into pieces that fit.
+func splitText(text string, b budget) []piece {
+	for _, s := range splitters {
+		parts := s.split(text)
+		if len(parts) < 2 {
+			continue
+		}
+
+		var (
+			out    []piece
+			cur    strings.Builder
+			curSep string
+			size   length
+		)
+		for _, part := range parts {
+			for i, p := range fit(part, b) {
+				if i == 0 {
+					p.sep = s.sep
+				}
+				next := b.length(p.text)
+				if cur.Len() > 0 {
+					if joined := size.plus(b.length(p.sep)).plus(next); b.within(joined) {
+						cur.WriteString(p.sep)
+						cur.WriteString(p.text)
+						size = joined
+						continue
+					}
+					out = append(out, piece{text: cur.String(), sep: curSep})
+					cur.Reset()
+				}
+				cur.WriteString(p.text)
+				curSep, size = p.sep, next
+			}
+		}
+		if cur.Len() > 0 {
+			out = append(out, piece{text: cur.String(), sep: curSep})
+		}
+		return out
+	}
+
+	return splitRunes(text, b)
+}
+
+/*
+splitBlock splits the lines of a code block or table that's too big, repeating its head (the opening
+fence, or the header and delimiter rows) and tail (the closing fence) around every piece so that each
+piece is still a block of its own. Lines too long to fit even between the head and tail are split like
+any text along with them, and a head too long to be repeated is kept once at the start of the block.
+*/
+func splitBlock(head string, body []string, tail string, b budget) []piece {
+	wrap := func(lines []string) string {
+		parts := append([]string{head}, lines...)
+		if tail != "" {
+			parts = append(parts, tail)
+		}
+		return strings.Join(parts, lineSep)
+	}
+
+	sep := b.length(lineSep)
+	frame := b.length(head)
+	if tail != "" {
+		frame = frame.plus(sep).plus(b.length(tail))
+	}
+	if !b.within(frame) {
+		return splitText(wrap(body), b)
+	}
+
+	var (
+		out  []piece
+		cur  []string
+		size length
+	)
+	flush := func() {
+		for len(cur) > 0 && strings.TrimSpace(cur[len(cur)-1]) == "" {
+			cur = cur[:len(cur)-1]
+		}
+		if len(cur) == 0 {
+			return
+		}
+		text := wrap(cur)
+		if b.fits(text) {
+			out = append(out, piece{text: text, sep: lineSep})
+		} else {
+			for i, p := range splitText(text, b) {
+				if i == 0 {
+					p.sep = lineSep
+				}
+				out = append(out, p)
+			}
+		}
+		cur = nil
+	}
+
+	for _, line := range body {
+		next := b.length(line)
+		if len(cur) > 0 && !b.within(size.plus(sep).plus(next)) {
+			flush()
+		}
+		if len(cur) == 0 {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			size = frame
+		}
+		cur = append(cur, line)
+		size = size.plus(sep).plus(next)
+	}
+	flush()
+
+	return out
+}
+
+var tableDelimiter = regexp.MustCompile(`^\|?(\s*:?-+:?\s*\|)*\s*:?-+:?\s*\|?$`)
+
+/*
+markdownBlock reports whether text is a fenced code block or a GFM table, as rendered by the markdown
+package, and returns the lines that have to be repeated when it's split and the lines in between.
+*/
+func markdownBlock(text string) (head string, body []string, tail string, ok bool) {
+	lines := strings.Split(text, lineSep)
+	if len(lines) < 3 {
+		return "", nil, "", false
+	}
+
+	if fence := openingFence(lines[0]); fence != "" && closesFence(lines[len(lines)-1], fence) {
+		return lines[0], lines[1 : len(lines)-1], lines[len(lines)-1], true
+	}
+
+	for _, line := range lines {
+		if !strings.HasPrefix(strings.TrimSpace(line), "|") {
+			return "", nil, "", false
+		}
+	}
+	if tableDelimiter.MatchString(strings.TrimSpace(lines[1])) {
+		return lines[0] + lineSep + lines[1], lines[2:], "", true
+	}
+
+	return "", nil, "", false
+}
+
+// openingFence returns the backticks or tildes opening a fenced code block on line, if it opens one.
+func openingFence(line string) string {
+	line = strings.TrimLeft(line, " ")
+	for _, c := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, c))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// closesFence reports whether line closes the code block opened by fence.
+func closesFence(line, fence string) bool {
+	line = strings.TrimSpace(line)
+	return strings.HasPrefix(line, fence) && strings.Trim(line, fence[:1]) == ""
+}
+
+// pack greedily joins units into chunks that fit within limit, repeating up to overlap of the previous chunk.
+func pack(units []unit, b budget) []common.Chunk {
+	var (
+		out     []common.Chunk
+		current []unit
+		size    length
+	)
+
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		var content strings.Builder
+		for i, u := range current {
+			if i > 0 {
+				content.WriteString(u.sep)
+			}
+			content.WriteString(u.text)
+		}
+		out = append(out, common.Chunk{
+			Content:  content.String(),
+			Source:   current[0].source,
+			Metadata: current[0].meta,
+		})
+	}
+
+	for _, u := range units {
+		next := b.length(u.text)
+		if len(current) > 0 {
+			next = size.plus(b.length(u.sep)).plus(next)
+			if b.limited() && !b.within(next) {
+				flush()
+				current = overlapTail(current, u, b)
+				next = b.joinedLength(append(current, u))
+			}
+		}
+		current = append(current, u)
+		size = next
+	}
+	flush()
+
+	return out
+}
+
+// joinedLength is the length of units once they're joined into a chunk.
+func (b budget) joinedLength(units []unit) length {
+	var l length
+	for i, u := range units {
+		if i > 0 {
+			l = l.plus(b.length(u.sep))
+		}
+		l = l.plus(b.length(u.text))
+	}
+	return l
+}
+
+/*
+overlapTail returns the trailing units of a chunk that fit in overlap, these are repeated at
+the start of the next chunk so context isn't lost at the boundary.
+*/
+func overlapTail(prev []unit, next unit, b budget) []unit {
+	if b.overlap <= 0 {
+		return nil
+	}
+
+	var tail []unit
+	total := 0
+	for i := len(prev) - 1; i >= 0; i-- {
+		total += b.size(b.length(prev[i].sep).plus(b.length(prev[i].text)))
+		if total > b.overlap {
+			break
+		}
+		tail = append([]unit{prev[i]}, tail...)
+	}
+
+	if len(tail) == 0 {
+		last := prev[len(prev)-1]
+		words := strings.Fields(last.text)
+		var kept []string
+		total = 0
+		for i := len(words) - 1; i >= 0; i-- {
+			total += b.size(b.length(wordSep).plus(b.length(words[i])))
+			if total > b.overlap {
+				break
+			}
+			kept = append([]string{words[i]}, kept...)
+		}
+		if len(kept) == 0 {
+			return nil
+		}
+		tail = []unit{{text: strings.Join(kept, wordSep), sep: last.sep, source: last.source, meta: last.meta}}
+	}
+
+	// the overlap is a nice to have, it should never push the next chunk over the limit
+	for len(tail) > 0 && b.limited() && !b.within(b.joinedLength(append(slices.Clone(tail), next))) {
+		tail = tail[1:]
+	}
+
+	return tail
+}
+
+// renumber resets the index of every chunk to its position within its source.
+func renumber(chunks []common.Chunk) {
+	seen := make(map[string]int)
+	for i := range chunks {
+		chunks[i].Metadata.Index = seen[chunks[i].Source]
+		seen[chunks[i].Source]++
+	}
+}
+
+// splitParagraphs splits text on blank lines, except for those within fenced code blocks.
+func splitParagraphs(text string) []string {
+	var (
+		paragraphs []string
+		current    []string
+		fence      string
+	)
+	for _, line := range strings.Split(text, lineSep) {
+		switch {
+		case fence != "":
+			current = append(current, line)
+			if closesFence(line, fence) {
+				fence = ""
+			}
+		case strings.TrimSpace(line) == "":
+			paragraphs = append(paragraphs, strings.Join(current, lineSep))
+			current = nil
+		default:
+			fence = openingFence(line)
+			current = append(current, line)
+		}
+	}
+	paragraphs = append(paragraphs, strings.Join(current, lineSep))
+
+	return nonEmpty(paragraphs)
+}
+
+// splitLines splits text into its non blank lines, keeping their indentation.
+func splitLines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, lineSep) {
+		if line = strings.TrimRight(line, " \t\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+/*
+splitSentences splits text after sentence ending punctuation that's followed by whitespace.
+It doesn't know about abbreviations so "e.g. this" will be split, that's good enough for sizing chunks.
+*/
+func splitSentences(text string) []string {
+	var (
+		sentences []string
+		start     int
+	)
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if !strings.ContainsRune(".!?", runes[i]) {
+			continue
+		}
+
+		j := i + 1
+		for j < len(runes) && strings.ContainsRune(`"')]`, runes[j]) {
+			j++
+		}
+		if j < len(runes) && unicode.IsSpace(runes[j]) {
+			sentences = append(sentences, string(runes[start:j]))
+			start = j
+			i = j
+		}
+	}
+	sentences = append(sentences, string(runes[start:]))
+
+	return nonEmpty(sentences)
+}
+
+/*
+splitRunes splits text that has no boundaries left to split on, the pieces are joined back without a
+separator. The longest prefix that fits is found by doubling and then halving its length, so that a
+long piece isn't measured again for every rune added to it.
+*/
+func splitRunes(text string, b budget) []piece {
+	var out []piece
+	runes := []rune(text)
+	for len(runes) > 0 {
+		fits := func(n int) bool { return b.fits(string(runes[:n])) }
+
+		hi := 1
+		for hi < len(runes) && fits(min(2*hi, len(runes))) {
+			hi = min(2*hi, len(runes))
+		}
+		if hi < len(runes) {
+			// hi fits and the next doubling doesn't, the longest prefix is somewhere in between
+			lo, end := hi, min(2*hi, len(runes))
+			hi = lo + sort.Search(end-lo, func(i int) bool { return !fits(lo + i + 1) })
+		}
+
+		out = append(out, piece{text: string(runes[:hi])})
+		runes = runes[hi:]
+	}
+	return out
+}
+
+func nonEmpty(parts []string) []string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return out
+}
@@ -0,0 +1,259 @@
+package chunker
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+func contents(chunks []common.Chunk) []string {
+	var out []string
+	for _, c := range chunks {
+		out = append(out, c.Content)
+	}
+	return out
+}
+
+func TestSplit(t *testing.T) {
+	section := func(s ...string) common.Metadata { return common.Metadata{Section: s} }
+
+	type args struct {
+		chunks []common.Chunk
+		opts   common.ChunkingOptions
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "no strategy leaves chunks untouched",
+			args: args{
+				chunks: []common.Chunk{{Content: "one"}, {Content: "two"}},
+				opts:   common.ChunkingOptions{MaxChars: 2},
+			},
+			want: []string{"one", "two"},
+		},
+		{
+			name: "paragraphs are merged up to the limit",
+			args: args{
+				chunks: []common.Chunk{{Content: "First one."}, {Content: "Second one."}, {Content: "Third one."}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkByParagraph, MaxChars: 25},
+			},
+			want: []string{"First one.\n\nSecond one.", "Third one."},
+		},
+		{
+			name: "a single document is split into paragraphs",
+			args: args{
+				chunks: []common.Chunk{{Content: "First one.\n\nSecond one.\n \nThird one."}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkByParagraph, MaxChars: 12},
+			},
+			want: []string{"First one.", "Second one.", "Third one."},
+		},
+		{
+			name: "sentences",
+			args: args{
+				chunks: []common.Chunk{{Content: "One. Two! Three? Four."}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkBySentence, MaxChars: 10},
+			},
+			want: []string{"One. Two!", "Three?", "Four."},
+		},
+		{
+			name: "oversized paragraphs are split on words",
+			args: args{
+				chunks: []common.Chunk{{Content: "alpha beta gamma delta"}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkByParagraph, MaxChars: 11},
+			},
+			want: []string{"alpha beta", "gamma delta"},
+		},
+		{
+			name: "oversized words are split on characters",
+			args: args{
+				chunks: []common.Chunk{{Content: "abcdefghij"}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkRecursive, MaxChars: 4},
+			},
+			want: []string{"abcd", "efgh", "ij"},
+		},
+		{
+			name: "sections are never merged",
+			args: args{
+				chunks: []common.Chunk{
+					{Content: "Install", Metadata: section("Install")},
+					{Content: "Run the installer.", Metadata: section("Install")},
+					{Content: "Usage", Metadata: section("Usage")},
+					{Content: "Run it.", Metadata: section("Usage")},
+				},
+				opts: common.ChunkingOptions{Strategy: common.ChunkRecursive, MaxChars: 1000},
+			},
+			want: []string{"Install\n\nRun the installer.", "Usage\n\nRun it."},
+		},
+		{
+			name: "pages are never merged",
+			args: args{
+				chunks: []common.Chunk{
+					{Content: "Page one.", Metadata: common.Metadata{Page: 1}},
+					{Content: "Page two.", Metadata: common.Metadata{Page: 2}},
+				},
+				opts: common.ChunkingOptions{Strategy: common.ChunkByParagraph},
+			},
+			want: []string{"Page one.", "Page two."},
+		},
+		{
+			name: "chapters are never merged",
+			args: args{
+				chunks: []common.Chunk{
+					{Content: "Chapter one.", Metadata: common.Metadata{Chapter: 1}},
+					{Content: "Chapter two.", Metadata: common.Metadata{Chapter: 2}},
+				},
+				opts: common.ChunkingOptions{Strategy: common.ChunkByParagraph},
+			},
+			want: []string{"Chapter one.", "Chapter two."},
+		},
+		{
+			name: "overlap repeats the end of the previous chunk",
+			args: args{
+				chunks: []common.Chunk{{Content: "One. Two. Three. Four."}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkBySentence, MaxChars: 12, Overlap: 6},
+			},
+			want: []string{"One. Two.", "Two. Three.", "Four."},
+		},
+		{
+			name: "lines are joined with newlines",
+			args: args{
+				chunks: []common.Chunk{{Content: "line one\nline two\nline three"}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkRecursive, MaxChars: 20},
+			},
+			want: []string{"line one\nline two", "line three"},
+		},
+		{
+			name: "tables are split on rows and keep their header",
+			args: args{
+				chunks: []common.Chunk{{Content: "| a | b |\n| --- | --- |\n| 1 | 2 |\n| 3 | 4 |"}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkRecursive, MaxChars: 40},
+			},
+			want: []string{"| a | b |\n| --- | --- |\n| 1 | 2 |", "| a | b |\n| --- | --- |\n| 3 | 4 |"},
+		},
+		{
+			name: "code blocks aren't split on blank lines",
+			args: args{
+				chunks: []common.Chunk{{Content: "Intro.\n\n```go\nif ok {\n\n\treturn\n}\n```"}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkBySentence, MaxChars: 30},
+			},
+			want: []string{"Intro.", "```go\nif ok {\n\n\treturn\n}\n```"},
+		},
+		{
+			name: "code blocks that are too big are fenced in every chunk",
+			args: args{
+				chunks: []common.Chunk{{Content: "```go\na := 1\n\nb := 2\n```"}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkByParagraph, MaxChars: 20},
+			},
+			want: []string{"```go\na := 1\n```", "```go\nb := 2\n```"},
+		},
+		{
+			name: "rows too long to fit with the header are split after it",
+			args: args{
+				chunks: []common.Chunk{{Content: "| a |\n| - |\n| one two three four |"}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkRecursive, MaxChars: 22},
+			},
+			want: []string{"| a |\n| - |", "| one two three four |"},
+		},
+		{
+			name: "headers too long to repeat are kept once",
+			args: args{
+				chunks: []common.Chunk{{Content: "| alpha | beta |\n| --- | --- |\n| 1 | 2 |\n| 3 | 4 |"}},
+				opts:   common.ChunkingOptions{Strategy: common.ChunkRecursive, MaxChars: 20},
+			},
+			want: []string{"| alpha | beta |", "| --- | --- |", "| 1 | 2 |\n| 3 | 4 |"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := contents(Split(tt.args.chunks, tt.args.opts, nil))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit_Limits(t *testing.T) {
+	text := strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 40)
+	opts := common.ChunkingOptions{Strategy: common.ChunkRecursive, MaxChars: 100, Overlap: 20}
+
+	got := Split([]common.Chunk{{Content: text, Source: "a"}, {Content: text, Source: "b"}}, opts, nil)
+	if len(got) < 2 {
+		t.Fatalf("Split() returned %d chunks", len(got))
+	}
+
+	index := map[string]int{}
+	for _, chunk := range got {
+		if n := utf8.RuneCountInString(chunk.Content); n > opts.MaxChars {
+			t.Errorf("chunk has %d characters, want at most %d", n, opts.MaxChars)
+		}
+		if chunk.Metadata.Index != index[chunk.Source] {
+			t.Errorf("chunk index = %d, want %d", chunk.Metadata.Index, index[chunk.Source])
+		}
+		index[chunk.Source]++
+	}
+}
+
+func Test_splitSentences(t *testing.T) {
+	got := splitSentences(`He said "hi." Then left! Why? Because (it was late.) The end`)
+	want := []string{`He said "hi."`, "Then left!", "Why?", "Because (it was late.)", "The end"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("splitSentences() = %q, want %q", got, want)
+	}
+}
+
+type wordCounter struct{}
+
+func (wordCounter) Count(text string) int {
+	return len(strings.Fields(text))
+}
+
+func TestSplit_MaxTokens(t *testing.T) {
+	chunks := []common.Chunk{{Content: "one two three. four five six seven. eight"}}
+
+	tests := []struct {
+		name      string
+		opts      common.ChunkingOptions
+		tokenizer common.Tokenizer
+		want      []string
+	}{
+		{
+			name:      "token budget",
+			opts:      common.ChunkingOptions{Strategy: common.ChunkBySentence, MaxTokens: 4},
+			tokenizer: wordCounter{},
+			want:      []string{"one two three.", "four five six seven.", "eight"},
+		},
+		{
+			name:      "token and character budgets",
+			opts:      common.ChunkingOptions{Strategy: common.ChunkBySentence, MaxTokens: 4, MaxChars: 15},
+			tokenizer: wordCounter{},
+			want:      []string{"one two three.", "four five six", "seven. eight"},
+		},
+		{
+			name:      "token budget without a tokenizer is ignored",
+			opts:      common.ChunkingOptions{Strategy: common.ChunkBySentence, MaxTokens: 1},
+			tokenizer: nil,
+			want:      []string{"one two three. four five six seven. eight"},
+		},
+		{
+			name:      "overlap is counted in tokens",
+			opts:      common.ChunkingOptions{Strategy: common.ChunkBySentence, MaxTokens: 6, Overlap: 2},
+			tokenizer: wordCounter{},
+			want:      []string{"one two three.", "two three. four five six seven.", "six seven. eight"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := contents(Split(chunks, tt.opts, tt.tokenizer))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
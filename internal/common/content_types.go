@@ -0,0 +1,16 @@
+package common
+
+const (
+	ContentTypeHTML     = "text/html"
+	ContentTypeText     = "text/plain"
+	ContentTypeXML      = "application/xml"
+	ContentTypeTextXML  = "text/xml"
+	ContentTypePDF      = "application/pdf"
+	ContentTypeCSV      = "text/csv"
+	ContentTypeJSON     = "application/json"
+	ContentTypeYAML     = "application/x-yaml"
+	ContentTypeMarkdown = "text/markdown"
+	ContentTypeEPUB     = "application/epub+zip"
+	ContentTypeDocx     = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	ContentTypePptx     = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
@@ -1,6 +1,9 @@
 package common
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"time"
 )
 
@@ -19,17 +22,140 @@ type TranscribeOptions struct {
 }
 
 type Config struct {
-	UserAgent       string
-	RetryLimit      int
-	RetryDelay      time.Duration
-	CrawlDelay      time.Duration
-	ProxyList       []string
-	RateLimit       time.Duration
-	RateBurst       int
-	IgnoreRobotsTxt bool
+	UserAgent             string
+	RetryLimit            int
+	RetryDelay            time.Duration
+	RetryPolicy           RetryPolicy
+	CrawlDelay            time.Duration
+	ProxyList             []string
+	RateLimit             time.Duration
+	RateBurst             int
+	HostRateLimit         time.Duration
+	HostRateBurst         int
+	HostRateLimits        map[string]HostRate
+	IgnoreRobotsTxt       bool
+	RobotsCacheTTL        time.Duration
+	RobotsTags            RobotsTagPolicy
+	MaxConcurrency        int
+	MaxConcurrencyPerHost int
+	Chunking              ChunkingOptions
+	Tokenizer             Tokenizer
+	HTML                  HTMLOptions
+	Cache                 Cache
+	CacheChunks           bool
+	Files                 FileOptions
+	Archives              ArchiveOptions
+}
+
+type FileOptions struct {
+	Include        []string
+	Exclude        []string
+	FollowSymlinks bool
+	IncludeHidden  bool
+	MaxFileSize    int64
+}
+
+type ArchiveOptions struct {
+	MaxDepth     int
+	MaxEntries   int
+	MaxTotalSize int64
+	MaxRatio     float64
+}
+
+type OutputFormat string
+
+const (
+	FormatText     OutputFormat = ""
+	FormatMarkdown OutputFormat = "markdown"
+)
+
+type HTMLOptions struct {
+	Format         OutputFormat
+	Include        []string
+	Exclude        []string
+	Domains        map[string]Selectors
+	Readability    bool
+	DocumentHeader bool
+	Tables         TableFormat
+}
+
+type TableFormat string
+
+const (
+	TableMarkdown TableFormat = ""
+	TableRows     TableFormat = "rows"
+)
+
+type Selectors struct {
+	Include []string
+	Exclude []string
+}
+
+type RobotsTagPolicy string
+
+const (
+	RobotsTagsIgnore RobotsTagPolicy = ""
+	RobotsTagsSkip   RobotsTagPolicy = "skip"
+	RobotsTagsFlag   RobotsTagPolicy = "flag"
+)
+
+type HostRate struct {
+	RateLimit time.Duration
+	RateBurst int
+}
+
+var ErrCacheMiss = errors.New("cache miss")
+
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Put(ctx context.Context, key string, data []byte) error
+	Delete(ctx context.Context, key string) error
+}
+
+type RetryPolicy interface {
+	Retry(attempt int, err error) (time.Duration, bool)
+}
+
+type Tokenizer interface {
+	Count(text string) int
+}
+
+type ChunkStrategy string
+
+const (
+	ChunkNone        ChunkStrategy = ""
+	ChunkByParagraph ChunkStrategy = "paragraph"
+	ChunkBySentence  ChunkStrategy = "sentence"
+	ChunkRecursive   ChunkStrategy = "recursive"
+)
+
+type ChunkingOptions struct {
+	Strategy  ChunkStrategy
+	MaxChars  int
+	MaxTokens int
+	Overlap   int
 }
 
 type Chunk struct {
-	Content string
-	Source  string
+	Content  string
+	Source   string
+	Metadata Metadata
+}
+
+type Metadata struct {
+	ContentType string
+	Title       string
+	Section     []string
+	Page        int
+	Chapter     int
+	Index       int
+	ByteOffset  int64
+	FetchedAt   time.Time
+	Language    string
+	Tokens      int
+	Description string
+	Canonical   string
+	OpenGraph   map[string]string
+	JSONLD      []json.RawMessage
+	NoIndex     bool
 }
@@ -0,0 +1,40 @@
+package document
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"strings"
+)
+
+// coreProperties holds the document wide metadata stored in docProps/core.xml of OOXML files.
+type coreProperties struct {
+	Title    string `xml:"title"`
+	Language string `xml:"language"`
+}
+
+/*
+readCoreProperties reads the title and language of a DOCX or PPTX file. These are optional
+so any error is ignored and whatever could be read is returned.
+*/
+func readCoreProperties(zipReader *zip.Reader) coreProperties {
+	var props coreProperties
+
+	for _, file := range zipReader.File {
+		if file.Name != "docProps/core.xml" {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return props
+		}
+		defer rc.Close()
+
+		_ = xml.NewDecoder(rc).Decode(&props)
+		break
+	}
+
+	props.Title = strings.TrimSpace(props.Title)
+	props.Language = strings.TrimSpace(props.Language)
+	return props
+}
@@ -10,24 +10,23 @@ import (
 	"github.com/mmatongo/chew/v1/internal/utils"
 )
 
-func processDocxContent(r io.Reader) ([]string, error) {
+func processDocxContent(r io.Reader) ([]string, coreProperties, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return nil, coreProperties{}, err
 	}
 
 	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
-		return nil, err
+		return nil, coreProperties{}, err
 	}
 
 	var contents []string
-
 	for _, file := range zipReader.File {
 		if file.Name == "word/document.xml" {
 			contents, err = utils.ExtractTextFromXML(file)
 			if err != nil {
-				return nil, err
+				return nil, coreProperties{}, err
 			}
 			break
 		}
@@ -39,7 +38,7 @@ func processDocxContent(r io.Reader) ([]string, error) {
 		allContent.WriteString(" ")
 	}
 
-	return []string{allContent.String()}, nil
+	return []string{allContent.String()}, readCoreProperties(zipReader), nil
 
 	/*
 		// In the event we just want chunks we can just return contents
@@ -48,7 +47,7 @@ func processDocxContent(r io.Reader) ([]string, error) {
 }
 
 func ProcessDocx(r io.Reader, url string) ([]common.Chunk, error) {
-	content, err := processDocxContent(r)
+	content, props, err := processDocxContent(r)
 	if err != nil {
 		return nil, err
 	}
@@ -56,7 +55,15 @@ func ProcessDocx(r io.Reader, url string) ([]common.Chunk, error) {
 	var chunks []common.Chunk
 	for _, chunk := range content {
 		if strings.TrimSpace(string(chunk)) != "" {
-			chunks = append(chunks, common.Chunk{Content: string(chunk), Source: url})
+			chunks = append(chunks, common.Chunk{
+				Content: string(chunk),
+				Source:  url,
+				Metadata: common.Metadata{
+					Title:    props.Title,
+					Index:    len(chunks),
+					Language: props.Language,
+				},
+			})
 		}
 	}
 
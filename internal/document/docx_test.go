@@ -64,8 +64,9 @@ func TestProcessDocx(t *testing.T) {
 			},
 			want: []common.Chunk{
 				{
-					Content: "Hello from chew! ",
-					Source:  "http://example.com",
+					Content:  "Hello from chew! ",
+					Source:   "http://example.com",
+					Metadata: common.Metadata{},
 				},
 			},
 			wantErr: false,
@@ -86,7 +87,7 @@ func TestProcessDocx(t *testing.T) {
 }
 
 func TestProcessDocx_Error_ReadAll(t *testing.T) {
-	_, err := processPptxContent(&errorReader{})
+	_, _, err := processPptxContent(&errorReader{})
 	if err == nil {
 		t.Error("ProcessDocx() did not return an error, but one was expected")
 	}
@@ -8,10 +8,12 @@ import (
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/mmatongo/chew/v1/internal/common"
+	"github.com/mmatongo/chew/v1/internal/markdown"
+	"github.com/mmatongo/chew/v1/internal/text"
 	"github.com/taylorskalyo/goreader/epub"
 )
 
-func processEpubContent(r io.Reader) ([]common.Chunk, error) {
+func processEpubContent(r io.Reader, opts common.HTMLOptions) ([]common.Chunk, error) {
 	content, err := io.ReadAll(r)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read EPUB content: %w", err)
@@ -39,42 +41,67 @@ func processEpubContent(r io.Reader) ([]common.Chunk, error) {
 			return nil, fmt.Errorf("failed to open item %s: %w", item.HREF, err)
 		}
 
-		text, err := extractTextFromHTML(file)
+		chapter, err := extractTextFromHTML(file, opts)
 		file.Close()
 		if err != nil {
 			return nil, fmt.Errorf("failed to extract text from %s: %w", item.HREF, err)
 		}
 
-		text = strings.TrimSpace(text)
-		if text == "" {
+		chapter = strings.TrimSpace(chapter)
+		if chapter == "" {
 			continue
 		}
-		chunks = append(chunks, common.Chunk{Content: text, Source: item.HREF})
+		chunks = append(chunks, common.Chunk{
+			Content: chapter,
+			Source:  item.HREF,
+			Metadata: common.Metadata{
+				Title:    strings.TrimSpace(contents.Title),
+				Chapter:  len(chunks) + 1,
+				Index:    len(chunks),
+				Language: strings.TrimSpace(contents.Language),
+			},
+		})
 	}
 
 	return chunks, nil
 }
 
 func ProcessEpub(r io.Reader, url string) ([]common.Chunk, error) {
-	chunks, err := processEpubContent(r)
-	if err != nil {
-		return nil, err
-	}
+	return NewEpubProcessor(common.HTMLOptions{})(r, url)
+}
 
-	for i := range chunks {
-		chunks[i].Source = url
-	}
+// NewEpubProcessor returns an EPUB processor that extracts the chapters according to opts, the same way HTML pages are.
+func NewEpubProcessor(opts common.HTMLOptions) func(io.Reader, string) ([]common.Chunk, error) {
+	return func(r io.Reader, url string) ([]common.Chunk, error) {
+		chunks, err := processEpubContent(r, text.ResolveSelectors(opts, url))
+		if err != nil {
+			return nil, err
+		}
 
-	return chunks, nil
+		for i := range chunks {
+			chunks[i].Source = url
+		}
+
+		return chunks, nil
+	}
 }
 
-func extractTextFromHTML(r io.Reader) (string, error) {
+func extractTextFromHTML(r io.Reader, opts common.HTMLOptions) (string, error) {
 	doc, err := goquery.NewDocumentFromReader(r)
 	if err != nil {
 		return "", err
 	}
 
-	doc.Find("script, style,nav, header, footer").Remove()
+	doc.Find("script, style").Remove()
+
+	content, err := text.SelectContent(doc, opts)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.Format == common.FormatMarkdown {
+		return markdown.Render(content, markdown.Options{TableRows: opts.Tables == common.TableRows}), nil
+	}
 
 	var buf strings.Builder
 	/*
@@ -83,10 +110,8 @@ func extractTextFromHTML(r io.Reader) (string, error) {
 		for all HTML documents unfortunately.
 		This is a known issue and I'm working on a better solution.
 		see: https://github.com/mmatongo/chew/issues/22
-
-		TODO: Allow users to specify a CSS selector to extract text from
 	*/
-	doc.Find("p, h1, h2, h3, h4, h5, h6, li").Each(func(_ int, s *goquery.Selection) {
+	content.Find("p, h1, h2, h3, h4, h5, h6, li").Each(func(_ int, s *goquery.Selection) {
 		buf.WriteString(strings.TrimSpace(s.Text()))
 		buf.WriteString("\n\n")
 	})
@@ -34,6 +34,11 @@ func Test_processEpubContent(t *testing.T) {
 				{
 					Content: "A pdf for testing",
 					Source:  "index.html",
+					Metadata: common.Metadata{
+						Title:    "test.pdf",
+						Chapter:  1,
+						Language: "en",
+					},
 				},
 			},
 			wantErr: false,
@@ -41,7 +46,7 @@ func Test_processEpubContent(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := processEpubContent(tt.args.r)
+			got, err := processEpubContent(tt.args.r, common.HTMLOptions{})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("processEpubContent() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -77,6 +82,11 @@ func TestProcessEpub(t *testing.T) {
 				{
 					Content: "A pdf for testing",
 					Source:  "https://example.com/test.epub",
+					Metadata: common.Metadata{
+						Title:    "test.pdf",
+						Chapter:  1,
+						Language: "en",
+					},
 				},
 			},
 			wantErr: false,
@@ -126,7 +136,8 @@ func TestProcessEpub(t *testing.T) {
 func Test_extractTextFromHTML(t *testing.T) {
 	file, _ := utils.OpenFile("testdata/invalid.html")
 	type args struct {
-		r io.Reader
+		r    io.Reader
+		opts common.HTMLOptions
 	}
 	tests := []struct {
 		name    string
@@ -142,6 +153,27 @@ func Test_extractTextFromHTML(t *testing.T) {
 			want:    "some content",
 			wantErr: false,
 		},
+		{
+			name: "markdown",
+			args: args{
+				r:    strings.NewReader("<html><body><h1>Title</h1><p>Some <em>content</em>.</p><ul><li>one</li></ul></body></html>"),
+				opts: common.HTMLOptions{Format: common.FormatMarkdown},
+			},
+			want:    "# Title\n\nSome *content*.\n\n- one",
+			wantErr: false,
+		},
+		{
+			name: "readability",
+			args: args{
+				r: strings.NewReader(`<html><body>
+					<div class="sidebar"><p>Other chapters, appendices, and the index, in case you need them.</p></div>
+					<div class="chapter"><p>It was a dark and stormy night, and the rain fell in torrents.</p></div>
+				</body></html>`),
+				opts: common.HTMLOptions{Readability: true},
+			},
+			want:    "It was a dark and stormy night, and the rain fell in torrents.",
+			wantErr: false,
+		},
 		{
 			name: "error",
 			args: args{
@@ -153,7 +185,7 @@ func Test_extractTextFromHTML(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := extractTextFromHTML(tt.args.r)
+			got, err := extractTextFromHTML(tt.args.r, tt.args.opts)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("extractTextFromHTML() error = %v, wantErr %v", err, tt.wantErr)
 				return
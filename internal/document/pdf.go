@@ -40,6 +40,10 @@ func ProcessPDF(r io.Reader, url string) ([]common.Chunk, error) {
 		chunks = append(chunks, common.Chunk{
 			Content: text,
 			Source:  fmt.Sprintf("%s#page=%d", url, i),
+			Metadata: common.Metadata{
+				Page:  i,
+				Index: len(chunks),
+			},
 		})
 	}
 
@@ -45,6 +45,9 @@ func TestProcessPDF(t *testing.T) {
 				{
 					Content: "Apdffortesting",
 					Source:  "https://example.com/test.pdf#page=1",
+					Metadata: common.Metadata{
+						Page: 1,
+					},
 				},
 			},
 			wantErr: false,
@@ -4,59 +4,81 @@ import (
 	"archive/zip"
 	"bytes"
 	"io"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/mmatongo/chew/v1/internal/common"
 	"github.com/mmatongo/chew/v1/internal/utils"
 )
 
-func processPptxContent(r io.Reader) ([]string, error) {
+var slideName = regexp.MustCompile(`^ppt/slides/slide(\d+)\.xml$`)
+
+// slide holds the text of a single slide along with its number in the presentation.
+type slide struct {
+	number  int
+	content string
+}
+
+func processPptxContent(r io.Reader) ([]slide, coreProperties, error) {
 	data, err := io.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return nil, coreProperties{}, err
 	}
 
 	zipReader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
 	if err != nil {
-		return nil, err
+		return nil, coreProperties{}, err
 	}
 
-	var contents []string
-
+	var slides []slide
 	for _, file := range zipReader.File {
-		if strings.HasPrefix(file.Name, "ppt/slides/") {
-			slideText, err := utils.ExtractTextFromXML(file)
-			if err != nil {
-				return nil, err
-			}
-			contents = append(contents, slideText...)
+		match := slideName.FindStringSubmatch(file.Name)
+		if match == nil {
+			continue
+		}
+
+		slideText, err := utils.ExtractTextFromXML(file)
+		if err != nil {
+			return nil, coreProperties{}, err
+		}
+
+		var content strings.Builder
+		for _, text := range slideText {
+			content.WriteString(text)
+			content.WriteString(" ")
 		}
-	}
 
-	var allContent strings.Builder
-	for _, content := range contents {
-		allContent.WriteString(content)
-		allContent.WriteString(" ")
+		number, _ := strconv.Atoi(match[1])
+		slides = append(slides, slide{number: number, content: content.String()})
 	}
 
-	return []string{allContent.String()}, nil
+	// zip entries aren't guaranteed to be in slide order
+	sort.Slice(slides, func(i, j int) bool { return slides[i].number < slides[j].number })
 
-	/*
-		// In the event we just want chunks we can just return contents
-		return contents, nil
-	*/
+	return slides, readCoreProperties(zipReader), nil
 }
 
 func ProcessPptx(r io.Reader, url string) ([]common.Chunk, error) {
-	content, err := processPptxContent(r)
+	slides, props, err := processPptxContent(r)
 	if err != nil {
 		return nil, err
 	}
 
 	var chunks []common.Chunk
-	for _, chunk := range content {
-		if strings.TrimSpace(string(chunk)) != "" {
-			chunks = append(chunks, common.Chunk{Content: string(chunk), Source: url})
+	for _, s := range slides {
+		if strings.TrimSpace(s.content) != "" {
+			chunks = append(chunks, common.Chunk{
+				Content: s.content,
+				Source:  url,
+				Metadata: common.Metadata{
+					Title:    props.Title,
+					Page:     s.number,
+					Index:    len(chunks),
+					Language: props.Language,
+				},
+			})
 		}
 	}
 
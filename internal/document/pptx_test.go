@@ -45,9 +45,13 @@ func TestProcessPptx(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name:    "Single paragraph pptx file",
-			args:    args{r: createSingleParagraphPptx("Hello from chew!"), url: "http://example.com"},
-			want:    []common.Chunk{{Content: "Hello from chew! ", Source: "http://example.com"}},
+			name: "Single paragraph pptx file",
+			args: args{r: createSingleParagraphPptx("Hello from chew!"), url: "http://example.com"},
+			want: []common.Chunk{{
+				Content:  "Hello from chew! ",
+				Source:   "http://example.com",
+				Metadata: common.Metadata{Page: 1},
+			}},
 			wantErr: false,
 		},
 	}
@@ -66,8 +70,39 @@ func TestProcessPptx(t *testing.T) {
 }
 
 func TestProcessPptx_Error_ReadAll(t *testing.T) {
-	_, err := processPptxContent(&errorReader{})
+	_, _, err := processPptxContent(&errorReader{})
 	if err == nil {
 		t.Error("ProcessPptx() did not return an error, but one was expected")
 	}
 }
+
+func TestProcessPptx_Slides(t *testing.T) {
+	buf := new(bytes.Buffer)
+	w := zip.NewWriter(buf)
+	for _, slide := range []struct{ name, text string }{
+		{"ppt/slides/slide10.xml", "Ten"},
+		{"ppt/slides/slide2.xml", "Two"},
+		{"ppt/slides/_rels/slide2.xml.rels", ""},
+		{"ppt/slides/slide1.xml", "One"},
+	} {
+		f, _ := w.Create(slide.name)
+		f.Write([]byte(`<document><p>` + slide.text + `</p></document>`))
+	}
+	f, _ := w.Create("docProps/core.xml")
+	f.Write([]byte(`<cp:coreProperties xmlns:cp="cp" xmlns:dc="dc"><dc:title>Deck</dc:title><dc:language>en-GB</dc:language></cp:coreProperties>`))
+	w.Close()
+
+	got, err := ProcessPptx(bytes.NewReader(buf.Bytes()), "http://example.com/deck.pptx")
+	if err != nil {
+		t.Fatalf("ProcessPptx() error = %v", err)
+	}
+
+	want := []common.Chunk{
+		{Content: "One ", Source: "http://example.com/deck.pptx", Metadata: common.Metadata{Title: "Deck", Page: 1, Index: 0, Language: "en-GB"}},
+		{Content: "Two ", Source: "http://example.com/deck.pptx", Metadata: common.Metadata{Title: "Deck", Page: 2, Index: 1, Language: "en-GB"}},
+		{Content: "Ten ", Source: "http://example.com/deck.pptx", Metadata: common.Metadata{Title: "Deck", Page: 10, Index: 2, Language: "en-GB"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ProcessPptx() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,240 @@
+/*
+Package fileset expands directories and glob patterns into the files they hold. Patterns use slash
+separated paths, where a ** segment matches any number of directories.
+*/
+package fileset
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+// File is a regular file found by Expand.
+type File struct {
+	Path string
+	Size int64
+}
+
+// HasMeta reports whether p holds any of the special characters of a glob pattern.
+func HasMeta(p string) bool {
+	return strings.ContainsAny(p, "*?[")
+}
+
+/*
+Split splits a glob pattern into the directory walked to expand it, made of the segments before the
+first one with special characters, and the pattern the paths relative to that directory must match.
+*/
+func Split(pattern string) (root, rel string) {
+	segments := strings.Split(filepath.ToSlash(pattern), "/")
+
+	i := 0
+	for i < len(segments) && !HasMeta(segments[i]) {
+		i++
+	}
+
+	root = strings.Join(segments[:i], "/")
+	switch {
+	case root == "" && strings.HasPrefix(pattern, "/"):
+		root = "/"
+	case root == "":
+		root = "."
+	}
+
+	return filepath.FromSlash(root), strings.Join(segments[i:], "/")
+}
+
+// Match reports whether the slash separated name matches pattern, a ** segment matches any number of segments.
+func Match(pattern, name string) bool {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			pattern = pattern[1:]
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchSegments(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
+
+/*
+couldContain reports whether the directory dir, a slash separated path, could hold a file matching
+pattern: the segments of dir have to match the leading segments of pattern and leave at least one for
+the file, up to a ** segment which may match any directory.
+*/
+func couldContain(pattern, dir string) bool {
+	segments := strings.Split(pattern, "/")
+	for i, name := range strings.Split(dir, "/") {
+		if i >= len(segments)-1 {
+			return segments[len(segments)-1] == "**"
+		}
+		if segments[i] == "**" {
+			return true
+		}
+		if ok, _ := path.Match(segments[i], name); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+matchAny reports whether rel matches any of patterns. Patterns without a slash are matched against the
+name of the file alone, the others against its whole path relative to the directory being expanded.
+*/
+func matchAny(patterns []string, rel string) bool {
+	for _, p := range patterns {
+		p = filepath.ToSlash(p)
+		target := rel
+		if !strings.Contains(p, "/") {
+			target = path.Base(rel)
+		}
+		if Match(p, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func validate(patterns ...string) error {
+	for _, p := range patterns {
+		for _, segment := range strings.Split(filepath.ToSlash(p), "/") {
+			if _, err := path.Match(segment, ""); err != nil {
+				return fmt.Errorf("invalid pattern %q: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+/*
+Expand walks root recursively and returns the regular files under it that match pattern, if it isn't
+empty, and pass the include and exclude patterns of opts. Hidden files and directories (starting with
+a dot) are skipped unless opts.IncludeHidden is set, and so are symbolic links unless
+opts.FollowSymlinks is set, in which case links looping back to a directory already walked are
+ignored. Directories that can't hold a match of pattern aren't walked. Files are returned in lexical
+order. opts.MaxFileSize is left to the caller.
+
+Directories that can't be read don't stop the walk, the files found elsewhere are returned along with
+an error joining every failure.
+*/
+func Expand(root, pattern string, opts common.FileOptions) ([]File, error) {
+	if err := validate(append(append([]string{pattern}, opts.Include...), opts.Exclude...)...); err != nil {
+		return nil, err
+	}
+
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, err
+	}
+
+	w := walker{
+		pattern: pattern,
+		opts:    opts,
+		visited: map[string]bool{realRoot: true},
+	}
+	if err := w.walk(root, ""); err != nil {
+		return nil, err
+	}
+
+	return w.files, errors.Join(w.errs...)
+}
+
+type walker struct {
+	pattern string
+	opts    common.FileOptions
+	visited map[string]bool
+	files   []File
+	errs    []error
+}
+
+func (w *walker) walk(dir, rel string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !w.opts.IncludeHidden && strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		var (
+			full    = filepath.Join(dir, name)
+			relPath = path.Join(rel, name)
+		)
+
+		info, err := entry.Info()
+		if err != nil {
+			w.errs = append(w.errs, err)
+			continue
+		}
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if !w.opts.FollowSymlinks {
+				continue
+			}
+			if info, err = os.Stat(full); err != nil {
+				w.errs = append(w.errs, err)
+				continue
+			}
+		}
+
+		switch {
+		case info.IsDir():
+			if matchAny(w.opts.Exclude, relPath) || (w.pattern != "" && !couldContain(w.pattern, relPath)) {
+				continue
+			}
+			real, err := filepath.EvalSymlinks(full)
+			if err != nil {
+				w.errs = append(w.errs, err)
+				continue
+			}
+			if w.visited[real] {
+				continue
+			}
+			w.visited[real] = true
+			if err := w.walk(full, relPath); err != nil {
+				w.errs = append(w.errs, err)
+			}
+		case info.Mode().IsRegular():
+			if w.pattern != "" && !Match(w.pattern, relPath) {
+				continue
+			}
+			if len(w.opts.Include) > 0 && !matchAny(w.opts.Include, relPath) {
+				continue
+			}
+			if matchAny(w.opts.Exclude, relPath) {
+				continue
+			}
+			w.files = append(w.files, File{Path: full, Size: info.Size()})
+		}
+	}
+
+	return nil
+}
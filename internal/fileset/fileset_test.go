@@ -0,0 +1,193 @@
+package fileset
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "*.pdf", name: "a.pdf", want: true},
+		{pattern: "*.pdf", name: "dir/a.pdf", want: false},
+		{pattern: "**/*.pdf", name: "a.pdf", want: true},
+		{pattern: "**/*.pdf", name: "x/y/a.pdf", want: true},
+		{pattern: "docs/**", name: "docs/a/b.md", want: true},
+		{pattern: "docs/**/b.md", name: "docs/b.md", want: true},
+		{pattern: "docs/**/b.md", name: "other/b.md", want: false},
+		{pattern: "a/?.txt", name: "a/1.txt", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.name); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_couldContain(t *testing.T) {
+	tests := []struct {
+		pattern string
+		dir     string
+		want    bool
+	}{
+		{pattern: "*.pdf", dir: "docs", want: false},
+		{pattern: "docs/*.pdf", dir: "docs", want: true},
+		{pattern: "docs/*.pdf", dir: "other", want: false},
+		{pattern: "docs/*.pdf", dir: "docs/deep", want: false},
+		{pattern: "*/*.pdf", dir: "docs", want: true},
+		{pattern: "**/*.pdf", dir: "a/b/c", want: true},
+		{pattern: "docs/**", dir: "docs/a/b", want: true},
+		{pattern: "docs/**/b.md", dir: "other/a", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.dir, func(t *testing.T) {
+			if got := couldContain(tt.pattern, tt.dir); got != tt.want {
+				t.Errorf("couldContain(%q, %q) = %v, want %v", tt.pattern, tt.dir, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		wantRoot string
+		wantRel  string
+	}{
+		{pattern: "/data/**/*.pdf", wantRoot: "/data", wantRel: "**/*.pdf"},
+		{pattern: "/data/reports/2024-*.csv", wantRoot: "/data/reports", wantRel: "2024-*.csv"},
+		{pattern: "/*.md", wantRoot: "/", wantRel: "*.md"},
+		{pattern: "*.md", wantRoot: ".", wantRel: "*.md"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			root, rel := Split(tt.pattern)
+			if root != tt.wantRoot || rel != tt.wantRel {
+				t.Errorf("Split(%q) = %q, %q, want %q, %q", tt.pattern, root, rel, tt.wantRoot, tt.wantRel)
+			}
+		})
+	}
+}
+
+func TestExpand(t *testing.T) {
+	root := t.TempDir()
+	for name, content := range map[string]string{
+		"a.pdf":             "a",
+		"notes.md":          "notes",
+		"docs/b.pdf":        "b",
+		"docs/deep/c.md":    "c",
+		"docs/.draft.md":    "draft",
+		".git/config":       "git",
+		"vendor/d.md":       "d",
+		"outside/linked.md": "linked",
+	} {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(root, "outside"), filepath.Join(root, "docs", "link")); err != nil {
+		t.Fatal(err)
+	}
+	// a loop back to the root must not be walked forever
+	if err := os.Symlink(root, filepath.Join(root, "docs", "loop")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		pattern string
+		opts    common.FileOptions
+		want    []string
+	}{
+		{
+			name: "directory",
+			opts: common.FileOptions{Exclude: []string{"outside"}},
+			want: []string{"a.pdf", "docs/b.pdf", "docs/deep/c.md", "notes.md", "vendor/d.md"},
+		},
+		{
+			name:    "glob",
+			pattern: "**/*.pdf",
+			want:    []string{"a.pdf", "docs/b.pdf"},
+		},
+		{
+			name: "include and exclude",
+			opts: common.FileOptions{Include: []string{"*.md"}, Exclude: []string{"vendor/**", "outside"}},
+			want: []string{"docs/deep/c.md", "notes.md"},
+		},
+		{
+			name:    "hidden",
+			pattern: "docs/**",
+			opts:    common.FileOptions{IncludeHidden: true},
+			want:    []string{"docs/.draft.md", "docs/b.pdf", "docs/deep/c.md"},
+		},
+		{
+			name:    "symlinks",
+			pattern: "docs/**/*.md",
+			opts:    common.FileOptions{FollowSymlinks: true},
+			want:    []string{"docs/deep/c.md", "docs/link/linked.md"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files, err := Expand(root, tt.pattern, tt.opts)
+			if err != nil {
+				t.Fatalf("Expand() error = %v", err)
+			}
+
+			var got []string
+			for _, f := range files {
+				rel, err := filepath.Rel(root, f.Path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, filepath.ToSlash(rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expand() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Expand(root, "[", common.FileOptions{}); err == nil {
+		t.Error("Expand() with an invalid pattern error = nil")
+	}
+}
+
+func TestExpand_Pruning(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "deep"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.pdf"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// walking into deep would fail on the dangling link
+	if err := os.Symlink(filepath.Join(root, "missing"), filepath.Join(root, "deep", "broken")); err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := Expand(root, "*.pdf", common.FileOptions{FollowSymlinks: true})
+	if err != nil {
+		t.Fatalf("Expand() error = %v", err)
+	}
+	if len(files) != 1 || files[0].Path != filepath.Join(root, "a.pdf") {
+		t.Errorf("Expand() = %v, want only a.pdf", files)
+	}
+
+	if _, err := Expand(root, "**/*.pdf", common.FileOptions{FollowSymlinks: true}); err == nil {
+		t.Error("Expand() walking deep error = nil, want the dangling link reported")
+	}
+}
@@ -0,0 +1,422 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+/*
+Block is a top level block of a document rendered as markdown, i.e. a heading, a paragraph,
+a whole list or a code block. Heading is the level of the heading (1-6) and 0 for anything else.
+*/
+type Block struct {
+	Markdown string
+	Heading  int
+	Text     string
+}
+
+var (
+	whitespace = regexp.MustCompile(`\s+`)
+	langClass  = regexp.MustCompile(`(?:^|\s)(?:lang|language)-(\S+)`)
+	// blockStart matches the start of a line that would be taken for a heading, list item, quote or rule
+	blockStart   = regexp.MustCompile(`^(?:#{1,6}(?:\s|$)|[-+](?:\s|$)|>|[=-]+$)`)
+	orderedStart = regexp.MustCompile(`^(\d{1,9})([.)])(\s|$)`)
+	// escaper escapes the characters of text that would otherwise be taken for inline markdown
+	escaper = strings.NewReplacer(`\`, `\\`, "`", "\\`", "*", `\*`, "_", `\_`, "[", `\[`, "]", `\]`, "~", `\~`)
+)
+
+// skipped elements never contain anything worth keeping.
+var skipped = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true,
+	"head": true, "iframe": true, "svg": true, "canvas": true, "button": true,
+	"input": true, "select": true, "textarea": true,
+}
+
+var inline = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "cite": true, "code": true,
+	"data": true, "del": true, "dfn": true, "em": true, "font": true, "i": true, "img": true,
+	"ins": true, "kbd": true, "label": true, "mark": true, "q": true, "s": true, "samp": true,
+	"small": true, "span": true, "strike": true, "strong": true, "sub": true, "sup": true,
+	"time": true, "u": true, "var": true, "br": true,
+}
+
+// Options controls how a document is rendered.
+type Options struct {
+	// TableRows renders every row of a table as its own block of "header: value" lines instead of a GFM table.
+	TableRows bool
+}
+
+// Render renders the selection as a markdown document.
+func Render(sel *goquery.Selection, opts Options) string {
+	var parts []string
+	for _, b := range Blocks(sel, opts) {
+		parts = append(parts, b.Markdown)
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Blocks renders the selection as a list of markdown blocks in document order.
+func Blocks(sel *goquery.Selection, opts Options) []Block {
+	r := &renderer{opts: opts}
+	for _, n := range sel.Nodes {
+		if n.Type == html.ElementNode && !skipped[n.Data] && (inline[n.Data] || isBlockLeaf(n.Data)) {
+			r.node(n)
+		} else {
+			r.children(n)
+		}
+	}
+	r.flush()
+	return r.blocks
+}
+
+type renderer struct {
+	opts    Options
+	blocks  []Block
+	pending strings.Builder
+}
+
+func (r *renderer) add(b Block) {
+	if strings.TrimSpace(b.Markdown) == "" {
+		return
+	}
+	r.blocks = append(r.blocks, b)
+}
+
+// flush turns any loose inline content collected so far into a paragraph.
+func (r *renderer) flush() {
+	text := paragraph(r.pending.String())
+	r.pending.Reset()
+	if text != "" {
+		r.add(Block{Markdown: text})
+	}
+}
+
+func (r *renderer) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		r.node(c)
+	}
+}
+
+func isBlockLeaf(name string) bool {
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "p", "ul", "ol", "pre", "blockquote", "table", "hr":
+		return true
+	}
+	return false
+}
+
+func (r *renderer) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		r.pending.WriteString(escapeText(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		r.children(n)
+		return
+	}
+
+	if skipped[n.Data] {
+		return
+	}
+
+	if inline[n.Data] {
+		writeInline(&r.pending, n)
+		return
+	}
+
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		r.flush()
+		level := int(n.Data[1] - '0')
+		text := strings.TrimSpace(collapse(renderInline(n)))
+		r.add(Block{
+			Markdown: strings.Repeat("#", level) + " " + text,
+			Heading:  level,
+			Text:     strings.TrimSpace(collapse(textContent(n))),
+		})
+	case "p":
+		r.flush()
+		r.add(Block{Markdown: paragraph(renderInline(n))})
+	case "ul", "ol":
+		r.flush()
+		r.add(Block{Markdown: strings.Join(renderList(n, ""), "\n")})
+	case "pre":
+		r.flush()
+		r.add(Block{Markdown: renderCode(n)})
+	case "blockquote":
+		r.flush()
+		inner := &renderer{opts: r.opts}
+		inner.children(n)
+		inner.flush()
+		r.add(Block{Markdown: quote(inner.blocks)})
+	case "table":
+		r.flush()
+		table := ParseTable(n, Cell)
+		if !r.opts.TableRows {
+			r.add(Block{Markdown: table.Markdown()})
+			return
+		}
+		for _, record := range table.Records() {
+			r.add(Block{Markdown: record})
+		}
+	case "hr":
+		r.flush()
+		r.add(Block{Markdown: "---"})
+	default:
+		r.flush()
+		r.children(n)
+		r.flush()
+	}
+}
+
+// renderInline renders the content of n as a single line of inline markdown.
+func renderInline(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeInline(&b, c)
+	}
+	return b.String()
+}
+
+func writeInline(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(escapeText(n.Data))
+		return
+	case html.ElementNode:
+	default:
+		return
+	}
+
+	if skipped[n.Data] {
+		return
+	}
+
+	wrap := func(marker string) {
+		inner := renderInline(n)
+		trimmed := strings.TrimSpace(inner)
+		if trimmed == "" {
+			b.WriteString(inner)
+			return
+		}
+		// keep the surrounding whitespace outside of the markers or they won't be recognised
+		lead := inner[:len(inner)-len(strings.TrimLeft(inner, " "))]
+		trail := inner[len(strings.TrimRight(inner, " ")):]
+		b.WriteString(lead + marker + trimmed + marker + trail)
+	}
+
+	switch n.Data {
+	case "em", "i":
+		wrap("*")
+	case "strong", "b":
+		wrap("**")
+	case "del", "s", "strike":
+		wrap("~~")
+	case "code", "kbd", "samp":
+		b.WriteString(inlineCode(textContent(n)))
+	case "a":
+		text := strings.TrimSpace(renderInline(n))
+		href := strings.TrimSpace(attr(n, "href"))
+		switch {
+		case text == "":
+		case href == "" || strings.HasPrefix(href, "javascript:"):
+			b.WriteString(text)
+		default:
+			fmt.Fprintf(b, "[%s](%s)", text, escapeURL(href))
+		}
+	case "img":
+		src := strings.TrimSpace(attr(n, "src"))
+		if src != "" {
+			fmt.Fprintf(b, "![%s](%s)", strings.TrimSpace(escapeText(attr(n, "alt"))), escapeURL(src))
+		}
+	case "br":
+		// made a hard break by paragraph, text never has line breaks of its own once collapsed
+		b.WriteString("\n")
+	case "ul", "ol":
+		b.WriteString(" " + strings.Join(renderList(n, ""), " ") + " ")
+	default:
+		if !inline[n.Data] {
+			// block elements nested where only inline content makes sense, i.e. a <div> in a <li>
+			b.WriteString(" " + renderInline(n) + " ")
+			return
+		}
+		b.WriteString(renderInline(n))
+	}
+}
+
+/*
+renderList renders a list and any list nested in it, nested lists are indented
+by the width of their parent's marker so they line up with its content.
+*/
+func renderList(n *html.Node, indent string) []string {
+	ordered := n.Data == "ol"
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil {
+		number = start
+	}
+
+	var lines []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+
+		marker := "-"
+		if ordered {
+			marker = strconv.Itoa(number) + "."
+			number++
+		}
+
+		var (
+			pad    = indent + strings.Repeat(" ", len(marker)+1)
+			text   strings.Builder
+			parts  []string
+			nested []string
+		)
+		for c := li.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && (c.Data == "ul" || c.Data == "ol") {
+				nested = append(nested, renderList(c, pad)...)
+				continue
+			}
+			// code blocks keep their whitespace, the text around them goes on either side
+			if c.Type == html.ElementNode && c.Data == "pre" {
+				parts = append(parts, paragraph(text.String()), renderCode(c))
+				text.Reset()
+				continue
+			}
+			writeInline(&text, c)
+		}
+		parts = append(parts, paragraph(text.String()))
+
+		// the lines after the first are indented to line up with the content of the item
+		var content []string
+		for _, part := range parts {
+			if part != "" {
+				content = append(content, strings.Split(part, "\n")...)
+			}
+		}
+		if len(content) == 0 {
+			content = []string{""}
+		}
+		lines = append(lines, indent+marker+" "+content[0])
+		for _, line := range content[1:] {
+			lines = append(lines, strings.TrimRight(pad+line, " "))
+		}
+		lines = append(lines, nested...)
+	}
+	return lines
+}
+
+// renderCode renders a <pre> element as a fenced code block, keeping its whitespace intact.
+func renderCode(n *html.Node) string {
+	lang := codeLanguage(n)
+	for c := n.FirstChild; c != nil && lang == ""; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "code" {
+			lang = codeLanguage(c)
+		}
+	}
+
+	code := strings.Trim(textContent(n), "\n")
+
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+
+	return fence + lang + "\n" + code + "\n" + fence
+}
+
+func codeLanguage(n *html.Node) string {
+	if match := langClass.FindStringSubmatch(attr(n, "class")); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+func quote(blocks []Block) string {
+	var lines []string
+	for i, b := range blocks {
+		if i > 0 {
+			lines = append(lines, ">")
+		}
+		for _, line := range strings.Split(b.Markdown, "\n") {
+			lines = append(lines, strings.TrimRight("> "+line, " "))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func inlineCode(code string) string {
+	code = whitespace.ReplaceAllString(code, " ")
+	if strings.TrimSpace(code) == "" {
+		return code
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+// escapeText collapses the whitespace of text and escapes it so it's never taken for markdown.
+func escapeText(s string) string {
+	return escaper.Replace(collapse(s))
+}
+
+/*
+paragraph turns rendered inline markdown into the lines of a paragraph, the line breaks left by <br>
+become hard breaks and whatever would start another block at the start of a line is escaped.
+*/
+func paragraph(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(collapse(line))
+		switch {
+		case line == "":
+			continue
+		case orderedStart.MatchString(line):
+			line = orderedStart.ReplaceAllString(line, `$1\$2$3`)
+		case blockStart.MatchString(line):
+			line = `\` + line
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\\\n")
+}
+
+func escapeURL(u string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(u)
+}
+
+func collapse(s string) string {
+	return whitespace.ReplaceAllString(s, " ")
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "headings and paragraphs",
+			html: "<h1>Title</h1><p>Some <strong>bold</strong> and <em>italic</em> text.</p><h3>Sub</h3>",
+			want: "# Title\n\nSome **bold** and *italic* text.\n\n### Sub",
+		},
+		{
+			name: "links and images",
+			html: `<p>See <a href="/docs">the docs</a> <img src="/logo.png" alt="Logo"></p>`,
+			want: "See [the docs](/docs) ![Logo](/logo.png)",
+		},
+		{
+			name: "nested lists",
+			html: "<ul><li>one<ul><li>one.a</li></ul></li><li>two</li></ul><ol start=\"3\"><li>three<ol><li>inner</li></ol></li></ol>",
+			want: "- one\n  - one.a\n- two\n\n3. three\n   1. inner",
+		},
+		{
+			name: "code",
+			html: "<p>Run <code>go test</code></p><pre class=\"lang-go\">func main() {\n\tfmt.Println(\"```\")\n}\n</pre>",
+			want: "Run `go test`\n\n````go\nfunc main() {\n\tfmt.Println(\"```\")\n}\n````",
+		},
+		{
+			name: "escaped text",
+			html: "<p>2 * 3 = 6, snake_case and [brackets]</p><p>1. not a list</p><p># not a heading</p><p>Use <code>a_b*c</code></p>",
+			want: "2 \\* 3 = 6, snake\\_case and \\[brackets\\]\n\n1\\. not a list\n\n\\# not a heading\n\nUse `a_b*c`",
+		},
+		{
+			name: "line breaks",
+			html: "<p>First line<br>- second line<br></p><ul><li>one<br>two</li></ul>",
+			want: "First line\\\n\\- second line\n\n- one\\\n  two",
+		},
+		{
+			name: "code in a list item",
+			html: "<ol><li>Run<pre>go test\n  ./...</pre>then check the output</li><li>Done</li></ol>",
+			want: "1. Run\n   ```\n   go test\n     ./...\n   ```\n   then check the output\n2. Done",
+		},
+		{
+			name: "blockquote",
+			html: "<blockquote><p>First</p><p>Second</p></blockquote>",
+			want: "> First\n>\n> Second",
+		},
+		{
+			name: "table",
+			html: "<table><thead><tr><th>Plan</th><th>Price</th></tr></thead><tbody><tr><td>Pro</td><td>$10 | month</td></tr><tr><td>Free</td></tr></tbody></table>",
+			want: "| Plan | Price |\n| --- | --- |\n| Pro | $10 \\| month |\n| Free |  |",
+		},
+		{
+			name: "loose text and containers",
+			html: "<div>Loose <b>text</b><div><p>Nested</p></div></div><script>alert(1)</script><hr>",
+			want: "Loose **text**\n\nNested\n\n---",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Render(doc.Find("body"), Options{}); got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlocks_Headings(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<h2>The <code>go</code> tool</h2><p>text</p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blocks := Blocks(doc.Find("body"), Options{})
+	if len(blocks) != 2 {
+		t.Fatalf("Blocks() returned %d blocks, want 2", len(blocks))
+	}
+	if b := blocks[0]; b.Heading != 2 || b.Text != "The go tool" || b.Markdown != "## The `go` tool" {
+		t.Errorf("heading block = %+v", b)
+	}
+	if blocks[1].Heading != 0 {
+		t.Errorf("paragraph block heading = %d, want 0", blocks[1].Heading)
+	}
+}
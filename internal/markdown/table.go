@@ -0,0 +1,254 @@
+package markdown
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+/*
+maxSpan caps colspan and rowspan, and maxColumns and maxCells the size of the whole grid, so bogus
+values or a page full of spans can't blow it up. Columns and rows past the caps are dropped.
+*/
+const (
+	maxSpan    = 1000
+	maxColumns = 1000
+	maxCells   = 100_000
+)
+
+/*
+Table is an HTML table laid out on a grid, a cell spanning several rows or columns is repeated in
+every position it covers so each row can be read on its own. Header holds the column names, it's
+empty if the table has none.
+*/
+type Table struct {
+	Header []string
+	Rows   [][]string
+}
+
+/*
+ParseTable lays out the rows of a table on a grid, rendering each cell with text. The header comes from
+the rows of <thead> or, failing that, from the leading rows made up only of <th> cells. When there's more
+than one header row the names of each column are joined with " / ".
+*/
+func ParseTable(n *html.Node, text func(*html.Node) string) Table {
+	var (
+		grid     [][]string
+		isHeader []bool
+		cols     int
+		// pending holds the cells spanning down into the following rows, by column
+		pending = map[int]struct {
+			value string
+			rows  int
+		}{}
+	)
+
+	forEachRow(n, func(tr *html.Node, inHead bool) {
+		if len(grid)*cols >= maxCells {
+			return
+		}
+
+		var (
+			row   []string
+			col   int
+			allTH = true
+			cells = 0
+		)
+
+		fill := func() {
+			for col < maxColumns {
+				p, ok := pending[col]
+				if !ok {
+					return
+				}
+				row = append(row, p.value)
+				if p.rows--; p.rows == 0 {
+					delete(pending, col)
+				} else {
+					pending[col] = p
+				}
+				col++
+			}
+		}
+
+		for td := tr.FirstChild; td != nil; td = td.NextSibling {
+			if td.Type != html.ElementNode || (td.Data != "td" && td.Data != "th") {
+				continue
+			}
+			cells++
+			allTH = allTH && td.Data == "th"
+
+			fill()
+			value := text(td)
+			colspan, rowspan := span(td, "colspan"), span(td, "rowspan")
+			for i := 0; i < colspan && col < maxColumns; i++ {
+				row = append(row, value)
+				if rowspan > 1 {
+					pending[col] = struct {
+						value string
+						rows  int
+					}{value, rowspan - 1}
+				}
+				col++
+			}
+		}
+		fill()
+
+		// cells spanning down past the end of the row are still part of it, after empty cells for any gap
+		for _, c := range slices.Sorted(maps.Keys(pending)) {
+			if c < col {
+				continue
+			}
+			for col < c {
+				row = append(row, "")
+				col++
+			}
+			fill()
+		}
+
+		if cells == 0 {
+			return
+		}
+		grid = append(grid, row)
+		isHeader = append(isHeader, inHead || allTH)
+		cols = max(cols, len(row))
+	})
+
+	// rows got as wide as the widest one, which may only have come after the grid was full
+	if cols > 0 && len(grid)*cols > maxCells {
+		grid = grid[:maxCells/cols]
+	}
+	for i := range grid {
+		for len(grid[i]) < cols {
+			grid[i] = append(grid[i], "")
+		}
+	}
+
+	headerRows := 0
+	for headerRows < len(grid) && isHeader[headerRows] {
+		headerRows++
+	}
+
+	var t Table
+	if headerRows > 0 && headerRows < len(grid) {
+		t.Header = make([]string, cols)
+		for c := 0; c < cols; c++ {
+			var names []string
+			for _, row := range grid[:headerRows] {
+				if v := row[c]; v != "" && (len(names) == 0 || names[len(names)-1] != v) {
+					names = append(names, v)
+				}
+			}
+			t.Header[c] = strings.Join(names, " / ")
+		}
+		grid = grid[headerRows:]
+	}
+	t.Rows = grid
+
+	return t
+}
+
+/*
+Markdown renders the table as a GFM table. GFM tables need a header so the first row is used
+if the table doesn't have one.
+*/
+func (t Table) Markdown() string {
+	header, rows := t.Header, t.Rows
+	if header == nil {
+		if len(rows) == 0 {
+			return ""
+		}
+		header, rows = rows[0], rows[1:]
+	}
+
+	escape := func(v string) string {
+		return strings.ReplaceAll(whitespace.ReplaceAllString(v, " "), "|", `\|`)
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		b.WriteString("|")
+		for _, v := range row {
+			b.WriteString(" " + escape(v) + " |")
+		}
+		b.WriteString("\n")
+	}
+
+	writeRow(header)
+	b.WriteString("|" + strings.Repeat(" --- |", len(header)) + "\n")
+	for _, row := range rows {
+		writeRow(row)
+	}
+
+	return strings.TrimRight(b.String(), "\n")
+}
+
+/*
+Records renders every row as a list of "header: value" lines, empty cells are skipped. Columns
+without a name are called "Column N".
+*/
+func (t Table) Records() []string {
+	var records []string
+	for _, row := range t.Rows {
+		var lines []string
+		for i, v := range row {
+			if v == "" {
+				continue
+			}
+			name := ""
+			if i < len(t.Header) {
+				name = t.Header[i]
+			}
+			if name == "" {
+				name = "Column " + strconv.Itoa(i+1)
+			}
+			lines = append(lines, name+": "+v)
+		}
+		if len(lines) > 0 {
+			records = append(records, strings.Join(lines, "\n"))
+		}
+	}
+	return records
+}
+
+// Cell renders the content of a table cell as a single line of inline markdown.
+func Cell(n *html.Node) string {
+	return strings.TrimSpace(whitespace.ReplaceAllString(renderInline(n), " "))
+}
+
+// PlainCell renders the content of a table cell as a single line of plain text.
+func PlainCell(n *html.Node) string {
+	return strings.TrimSpace(collapse(textContent(n)))
+}
+
+func span(n *html.Node, key string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(attr(n, key)))
+	if err != nil || v < 1 {
+		return 1
+	}
+	return min(v, maxSpan)
+}
+
+// forEachRow calls fn for every row of a table, skipping the rows of nested tables.
+func forEachRow(table *html.Node, fn func(tr *html.Node, inHead bool)) {
+	var walk func(n *html.Node, inHead bool)
+	walk = func(n *html.Node, inHead bool) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "tr":
+				fn(c, inHead)
+			case "thead":
+				walk(c, true)
+			case "tbody", "tfoot":
+				walk(c, false)
+			}
+		}
+	}
+	walk(table, false)
+}
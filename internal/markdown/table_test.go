@@ -0,0 +1,132 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func parseTable(t *testing.T, table string) Table {
+	t.Helper()
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(table))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ParseTable(doc.Find("table").Nodes[0], PlainCell)
+}
+
+func TestParseTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		table string
+		want  Table
+	}{
+		{
+			name:  "thead",
+			table: "<table><thead><tr><th>Plan</th><th>Price</th></tr></thead><tr><td>Pro</td><td>$10</td></tr></table>",
+			want:  Table{Header: []string{"Plan", "Price"}, Rows: [][]string{{"Pro", "$10"}}},
+		},
+		{
+			name:  "leading th row",
+			table: "<table><tr><th>Plan</th><th>Price</th></tr><tr><td>Free</td><td>$0</td></tr></table>",
+			want:  Table{Header: []string{"Plan", "Price"}, Rows: [][]string{{"Free", "$0"}}},
+		},
+		{
+			name:  "no header",
+			table: "<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>",
+			want:  Table{Rows: [][]string{{"a", "b"}, {"c", ""}}},
+		},
+		{
+			name: "colspan and rowspan",
+			table: `<table>
+				<thead>
+					<tr><th rowspan="2">Plan</th><th colspan="2">Price</th></tr>
+					<tr><th>Monthly</th><th>Yearly</th></tr>
+				</thead>
+				<tbody>
+					<tr><td>Pro</td><td rowspan="2">$10</td><td>$100</td></tr>
+					<tr><td>Team</td><td>$90</td></tr>
+					<tr><td colspan="3">Contact us for more</td></tr>
+				</tbody>
+			</table>`,
+			want: Table{
+				Header: []string{"Plan", "Price / Monthly", "Price / Yearly"},
+				Rows: [][]string{
+					{"Pro", "$10", "$100"},
+					{"Team", "$10", "$90"},
+					{"Contact us for more", "Contact us for more", "Contact us for more"},
+				},
+			},
+		},
+		{
+			name:  "rowspan after a gap",
+			table: "<table><tr><td>a</td><td>b</td><td rowspan=\"2\">c</td></tr><tr><td>d</td></tr><tr><td>e</td></tr></table>",
+			want:  Table{Rows: [][]string{{"a", "b", "c"}, {"d", "", "c"}, {"e", "", ""}}},
+		},
+		{
+			name:  "nested tables",
+			table: "<table><tr><td>outer<table><tr><td>inner</td></tr></table></td></tr></table>",
+			want:  Table{Rows: [][]string{{"outerinner"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTable(t, tt.table); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTable() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTable_Caps(t *testing.T) {
+	tests := []struct {
+		name     string
+		table    string
+		wantRows int
+		wantCols int
+	}{
+		{
+			name:     "columns",
+			table:    "<table><tr>" + strings.Repeat(`<td colspan="1000">x</td>`, 5) + "</tr></table>",
+			wantRows: 1,
+			wantCols: maxColumns,
+		},
+		{
+			name:     "cells",
+			table:    `<table><tr><td colspan="1000" rowspan="1000">x</td></tr>` + strings.Repeat("<tr><td>y</td></tr>", 500) + "</table>",
+			wantRows: maxCells / maxColumns,
+			wantCols: maxColumns,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseTable(t, tt.table)
+			if len(got.Rows) != tt.wantRows || len(got.Rows[0]) != tt.wantCols {
+				t.Errorf("ParseTable() = %d rows of %d columns, want %d of %d", len(got.Rows), len(got.Rows[0]), tt.wantRows, tt.wantCols)
+			}
+		})
+	}
+}
+
+func TestTable_Records(t *testing.T) {
+	table := Table{
+		Header: []string{"Plan", ""},
+		Rows:   [][]string{{"Pro", "$10"}, {"Free", ""}},
+	}
+
+	want := []string{"Plan: Pro\nColumn 2: $10", "Plan: Free"}
+	if got := table.Records(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Records() = %q, want %q", got, want)
+	}
+}
+
+func TestTable_Markdown(t *testing.T) {
+	table := Table{Header: []string{"Plan", "Note"}, Rows: [][]string{{"Pro", "a | b"}}}
+
+	want := "| Plan | Note |\n| --- | --- |\n| Pro | a \\| b |"
+	if got := table.Markdown(); got != want {
+		t.Errorf("Markdown() = %q, want %q", got, want)
+	}
+}
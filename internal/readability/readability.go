@@ -0,0 +1,296 @@
+package readability
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+/*
+These are the heuristics used by Mozilla's Readability, class names and ids matching unlikely are
+boilerplate unless they also match maybe, and positive and negative nudge the score of a candidate.
+*/
+var (
+	unlikely = regexp.MustCompile(`(?i)-ad-|ai2html|banner|breadcrumbs|combx|comment|community|cookie|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|newsletter|related|remark|replies|rss|share|shoutbox|sidebar|skyscraper|social|sponsor|supplemental|ad-break|agegate|pagination|pager|popup|yom-remote`)
+	maybe    = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow`)
+	positive = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story`)
+	negative = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|cookie|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget`)
+)
+
+const (
+	// minParagraphLen is the number of characters a paragraph needs to count towards the score of its ancestors.
+	minParagraphLen = 25
+	// maxAncestors is how far up the tree the score of a paragraph is propagated.
+	maxAncestors = 5
+)
+
+/*
+Extract returns the main content of root, dropping navigation, sidebars, comments and other boilerplate.
+
+Every paragraph adds to the score of its ancestors based on its length and number of commas, the closer
+the ancestor the bigger its share. Candidates start with a score based on their tag, class and id, and
+end up scaled down by their link density. The best candidate is kept along with any sibling that scores
+close enough to it or looks like a paragraph of the same article, then whatever is left inside it that's
+mostly links or looks like boilerplate is removed.
+
+If nothing looks like an article root is returned as is.
+*/
+func Extract(root *goquery.Selection) *goquery.Selection {
+	inRoot := make(map[*html.Node]bool, len(root.Nodes))
+	for _, n := range root.Nodes {
+		inRoot[n] = true
+	}
+
+	scores := make(map[*html.Node]float64)
+	root.Find("p, pre, td").Each(func(_ int, s *goquery.Selection) {
+		if isUnlikely(s.Nodes[0], inRoot) {
+			return
+		}
+
+		text := strings.TrimSpace(s.Text())
+		length := utf8.RuneCountInString(text)
+		if length < minParagraphLen {
+			return
+		}
+
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(length)/100, 3)
+
+		level := 0
+		for n := s.Nodes[0].Parent; n != nil && n.Type == html.ElementNode && level < maxAncestors; n = n.Parent {
+			if _, ok := scores[n]; !ok {
+				scores[n] = initialScore(n)
+			}
+
+			divider := 1.0
+			switch {
+			case level == 1:
+				divider = 2
+			case level > 1:
+				divider = float64(level * 3)
+			}
+			scores[n] += score / divider
+
+			if inRoot[n] {
+				break
+			}
+			level++
+		}
+	})
+
+	final := func(n *html.Node) float64 {
+		return scores[n] * (1 - linkDensity(n))
+	}
+
+	var (
+		best      *html.Node
+		bestScore float64
+	)
+	for n := range scores {
+		if score := final(n); best == nil || score > bestScore || (score == bestScore && before(n, best)) {
+			best, bestScore = n, score
+		}
+	}
+	if best == nil {
+		return root
+	}
+
+	if inRoot[best] {
+		return clean(root.FilterNodes(best))
+	}
+
+	threshold := max(10, bestScore*0.2)
+	keep := []*html.Node{best}
+	for sib := best.Parent.FirstChild; sib != nil; sib = sib.NextSibling {
+		if sib == best || sib.Type != html.ElementNode {
+			continue
+		}
+
+		if _, ok := scores[sib]; ok && final(sib) >= threshold {
+			keep = append(keep, sib)
+			continue
+		}
+
+		if sib.Data == "p" {
+			text := strings.TrimSpace(textContent(sib))
+			length := utf8.RuneCountInString(text)
+			density := linkDensity(sib)
+			if (length > 80 && density < 0.25) || (length > 0 && density == 0 && strings.Contains(text, ". ")) {
+				keep = append(keep, sib)
+			}
+		}
+	}
+
+	return clean(root.Find("*").FilterNodes(keep...))
+}
+
+// clean removes what's left of the boilerplate in the selected content.
+func clean(sel *goquery.Selection) *goquery.Selection {
+	sel.Find("form, iframe, button, input, select, textarea, aside").Remove()
+
+	sel.Find("*").Each(func(_ int, s *goquery.Selection) {
+		n := s.Nodes[0]
+		if n.Parent == nil || isProtected(n) {
+			return
+		}
+		if hint := classAndID(n); hint != "" && unlikely.MatchString(hint) && !maybe.MatchString(hint) {
+			s.Remove()
+			return
+		}
+		switch n.Data {
+		case "div", "section", "ul", "ol", "table":
+			if classWeight(n) < 0 || linkDensity(n) > 0.5 {
+				s.Remove()
+			}
+		}
+	})
+
+	return sel
+}
+
+// isUnlikely reports whether n or any of its ancestors up to root looks like boilerplate or is hidden.
+func isUnlikely(n *html.Node, root map[*html.Node]bool) bool {
+	for ; n != nil && n.Type == html.ElementNode; n = n.Parent {
+		if isHidden(n) {
+			return true
+		}
+		if hint := classAndID(n); !isProtected(n) && unlikely.MatchString(hint) && !maybe.MatchString(hint) {
+			return true
+		}
+		if root[n] {
+			break
+		}
+	}
+	return false
+}
+
+func isProtected(n *html.Node) bool {
+	switch n.Data {
+	case "html", "body", "article", "main":
+		return true
+	}
+	return false
+}
+
+func isHidden(n *html.Node) bool {
+	if _, ok := attr(n, "hidden"); ok {
+		return true
+	}
+	if v, _ := attr(n, "aria-hidden"); v == "true" {
+		return true
+	}
+	style, _ := attr(n, "style")
+	style = strings.ReplaceAll(strings.ToLower(style), " ", "")
+	return strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden")
+}
+
+func initialScore(n *html.Node) float64 {
+	score := float64(classWeight(n))
+	switch n.Data {
+	case "div", "article", "main":
+		score += 5
+	case "pre", "td", "blockquote":
+		score += 3
+	case "address", "ol", "ul", "dl", "dd", "dt", "li", "form":
+		score -= 3
+	case "h1", "h2", "h3", "h4", "h5", "h6", "th":
+		score -= 5
+	}
+	return score
+}
+
+func classWeight(n *html.Node) int {
+	weight := 0
+	for _, key := range []string{"class", "id"} {
+		v, _ := attr(n, key)
+		if v == "" {
+			continue
+		}
+		if negative.MatchString(v) {
+			weight -= 25
+		}
+		if positive.MatchString(v) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// linkDensity is the share of the text of n that's inside links.
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(strings.TrimSpace(textContent(n)))
+	if total == 0 {
+		return 0
+	}
+
+	links := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type == html.ElementNode && c.Data == "a" {
+				links += utf8.RuneCountInString(strings.TrimSpace(textContent(c)))
+				continue
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+
+	return float64(links) / float64(total)
+}
+
+// before reports whether a comes before b in the document, it keeps the choice of candidate deterministic.
+func before(a, b *html.Node) bool {
+	found := false
+	var walk func(*html.Node) bool
+	walk = func(n *html.Node) bool {
+		if n == a {
+			found = true
+			return true
+		}
+		if n == b {
+			return true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if walk(c) {
+				return true
+			}
+		}
+		return false
+	}
+
+	top := a
+	for top.Parent != nil {
+		top = top.Parent
+	}
+	walk(top)
+	return found
+}
+
+func classAndID(n *html.Node) string {
+	class, _ := attr(n, "class")
+	id, _ := attr(n, "id")
+	return strings.TrimSpace(class + " " + id)
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func attr(n *html.Node, key string) (string, bool) {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val, true
+		}
+	}
+	return "", false
+}
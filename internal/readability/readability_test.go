@@ -0,0 +1,65 @@
+package readability
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const page = `
+<html>
+<body>
+	<div class="site-header"><a href="/">Home</a> <a href="/blog">Blog</a> <a href="/about">About</a></div>
+	<div id="layout">
+		<div class="sidebar">
+			<p>Popular posts, all of them about things you already read last week, in no particular order.</p>
+			<ul><li><a href="/a">A post</a></li><li><a href="/b">Another post</a></li></ul>
+		</div>
+		<div class="post-body">
+			<h1>Making bread</h1>
+			<p>Bread is made from flour, water, salt and yeast, mixed together and left to rise for a few hours.</p>
+			<p>The dough is then shaped, proofed again and baked in a very hot oven, ideally with some steam.</p>
+			<div class="share-buttons"><a href="/share">Share</a> <a href="/tweet">Tweet</a></div>
+			<p>Good bread takes time, so plan ahead, and don't worry if the first loaves come out flat.</p>
+		</div>
+		<p>A closing note after the article body that still belongs to it. It has a few sentences.</p>
+		<div class="comments">
+			<p>Great post, thanks for sharing, I tried it and it worked, more or less, on the second try.</p>
+		</div>
+	</div>
+	<div class="footer"><p>Copyright, all rights reserved, do not copy this footer text anywhere else please.</p></div>
+</body>
+</html>`
+
+func TestExtract(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := Extract(doc.Find("body")).Text()
+
+	for _, want := range []string{"Making bread", "Bread is made from flour", "baked in a very hot oven", "Good bread takes time", "A closing note"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Extract() is missing %q", want)
+		}
+	}
+	for _, unwanted := range []string{"Popular posts", "Tweet", "Great post", "Copyright", "About"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("Extract() kept %q", unwanted)
+		}
+	}
+}
+
+func TestExtract_NoCandidates(t *testing.T) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body><h1>Short</h1><p>Too short.</p></body></html>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body := doc.Find("body")
+	if got := Extract(body); got.Text() != body.Text() {
+		t.Errorf("Extract() = %q, want the whole body %q", got.Text(), body.Text())
+	}
+}
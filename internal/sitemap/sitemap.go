@@ -0,0 +1,112 @@
+package sitemap
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxSize is the largest uncompressed sitemap allowed by the protocol.
+const maxSize = 50 << 20
+
+// URL is an entry of a sitemap or of a sitemap index, LastMod is zero when it isn't set or can't be parsed.
+type URL struct {
+	Loc     string
+	LastMod time.Time
+}
+
+/*
+Sitemap is a parsed sitemap. A regular sitemap only has URLs while a sitemap index only has Sitemaps,
+the locations of the sitemaps it's made of.
+*/
+type Sitemap struct {
+	URLs     []URL
+	Sitemaps []URL
+}
+
+type document struct {
+	URLs     []entry `xml:"url"`
+	Sitemaps []entry `xml:"sitemap"`
+}
+
+type entry struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// lastModFormats are the W3C datetime formats allowed in <lastmod>.
+var lastModFormats = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+}
+
+/*
+Parse reads a sitemap or a sitemap index, gzipped content (i.e. sitemap.xml.gz) is detected and
+decompressed automatically.
+*/
+func Parse(r io.Reader) (*Sitemap, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("decompressing sitemap: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = br
+	}
+
+	var doc document
+	if err := xml.NewDecoder(io.LimitReader(r, maxSize)).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parsing sitemap: %w", err)
+	}
+
+	sm := &Sitemap{}
+	for _, e := range doc.URLs {
+		if u, ok := e.url(); ok {
+			sm.URLs = append(sm.URLs, u)
+		}
+	}
+	for _, e := range doc.Sitemaps {
+		if u, ok := e.url(); ok {
+			sm.Sitemaps = append(sm.Sitemaps, u)
+		}
+	}
+
+	return sm, nil
+}
+
+func (e entry) url() (URL, bool) {
+	loc := strings.TrimSpace(e.Loc)
+	if loc == "" {
+		return URL{}, false
+	}
+	return URL{Loc: loc, LastMod: parseLastMod(e.LastMod)}, true
+}
+
+func parseLastMod(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range lastModFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+/*
+Modified reports whether u was modified at or after since. Entries without a lastmod are always
+considered modified since there's no way to tell.
+*/
+func (u URL) Modified(since time.Time) bool {
+	return since.IsZero() || u.LastMod.IsZero() || !u.LastMod.Before(since)
+}
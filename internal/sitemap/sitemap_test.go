@@ -0,0 +1,112 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const urlset = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<url><loc> https://example.com/a </loc><lastmod>2024-05-01</lastmod></url>
+	<url><loc>https://example.com/b</loc><lastmod>2024-05-02T10:30:00+02:00</lastmod></url>
+	<url><loc>https://example.com/c</loc></url>
+	<url><lastmod>2024-05-02</lastmod></url>
+</urlset>`
+
+const index = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+	<sitemap><loc>https://example.com/sitemap-1.xml.gz</loc><lastmod>2024-01-01T00:00Z</lastmod></sitemap>
+</sitemapindex>`
+
+func gzipped(t *testing.T, s string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	gz.Close()
+	return &buf
+}
+
+func TestParse(t *testing.T) {
+	wantURLs := &Sitemap{URLs: []URL{
+		{Loc: "https://example.com/a", LastMod: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{Loc: "https://example.com/b", LastMod: time.Date(2024, 5, 2, 8, 30, 0, 0, time.UTC)},
+		{Loc: "https://example.com/c"},
+	}}
+
+	tests := []struct {
+		name    string
+		content func(t *testing.T) *bytes.Buffer
+		want    *Sitemap
+		wantErr bool
+	}{
+		{
+			name:    "urlset",
+			content: func(*testing.T) *bytes.Buffer { return bytes.NewBufferString(urlset) },
+			want:    wantURLs,
+		},
+		{
+			name:    "gzipped",
+			content: func(t *testing.T) *bytes.Buffer { return gzipped(t, urlset) },
+			want:    wantURLs,
+		},
+		{
+			name:    "index",
+			content: func(*testing.T) *bytes.Buffer { return bytes.NewBufferString(index) },
+			want: &Sitemap{Sitemaps: []URL{
+				{Loc: "https://example.com/sitemap-1.xml.gz", LastMod: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+			}},
+		},
+		{
+			name:    "invalid",
+			content: func(*testing.T) *bytes.Buffer { return bytes.NewBufferString("<urlset><url>") },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.content(t))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, list := range [][]URL{got.URLs, got.Sitemaps} {
+				for i := range list {
+					list[i].LastMod = list[i].LastMod.UTC()
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestURL_Modified(t *testing.T) {
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		url  URL
+		want bool
+	}{
+		{name: "after", url: URL{LastMod: since.Add(time.Hour)}, want: true},
+		{name: "same time", url: URL{LastMod: since}, want: true},
+		{name: "before", url: URL{LastMod: since.Add(-time.Hour)}, want: false},
+		{name: "unknown", url: URL{}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.url.Modified(since); got != tt.want {
+				t.Errorf("Modified() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+/*
+Package sniff detects the type of content from its leading bytes, for sources that come without a
+content type (local files without a known extension) or with a useless one (application/octet-stream).
+*/
+package sniff
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+const (
+	ContentTypeXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	ContentTypeZip  = "application/zip"
+	ContentTypeTar  = "application/x-tar"
+	ContentTypeGzip = "application/gzip"
+	ContentTypeWAV  = "audio/wav"
+	ContentTypeFLAC = "audio/flac"
+	ContentTypeMP3  = "audio/mpeg"
+)
+
+var zipSignature = []byte("PK\x03\x04")
+
+// IsZip reports whether head is the start of a ZIP archive, the container of EPUB, OOXML and ODF documents.
+func IsZip(head []byte) bool {
+	return bytes.HasPrefix(head, zipSignature)
+}
+
+// IsGzip reports whether head is the start of a gzip stream.
+func IsGzip(head []byte) bool {
+	return bytes.HasPrefix(head, []byte{0x1F, 0x8B})
+}
+
+/*
+IsTar reports whether head is the start of a POSIX or GNU tar archive, which carry the "ustar" magic
+in the header of their first entry.
+*/
+func IsTar(head []byte) bool {
+	return len(head) >= 262 && bytes.Equal(head[257:262], []byte("ustar"))
+}
+
+/*
+Detect returns the content type of the content starting with head, or "" if it isn't recognized.
+ZIP based formats can only be told apart from head when the entry identifying them comes first, as
+the mimetype entry of EPUB and ODF documents does. Otherwise ContentTypeZip is returned and the
+archive has to be inspected with DetectZip.
+*/
+func Detect(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return common.ContentTypePDF
+	case IsZip(head):
+		return detectZipHead(head)
+	case IsGzip(head):
+		return ContentTypeGzip
+	case IsTar(head):
+		return ContentTypeTar
+	case len(head) >= 12 && bytes.HasPrefix(head, []byte("RIFF")) && bytes.Equal(head[8:12], []byte("WAVE")):
+		return ContentTypeWAV
+	case bytes.HasPrefix(head, []byte("fLaC")):
+		return ContentTypeFLAC
+	case bytes.HasPrefix(head, []byte("ID3")), len(head) >= 2 && head[0] == 0xFF && head[1]&0xE0 == 0xE0 && head[1]&0x06 != 0:
+		// an ID3 tag or the sync word of an MPEG audio frame with a valid layer
+		return ContentTypeMP3
+	}
+
+	return detectText(head)
+}
+
+// detectText recognizes HTML, XML and JSON, ignoring a byte order mark and leading whitespace.
+func detectText(head []byte) string {
+	head = bytes.TrimPrefix(head, []byte("\xEF\xBB\xBF"))
+	head = bytes.TrimLeft(head, " \t\r\n\f")
+	if len(head) == 0 {
+		return ""
+	}
+
+	lower := strings.ToLower(string(head[:min(len(head), 64)]))
+	for _, prefix := range []string{"<!doctype html", "<html", "<head", "<body"} {
+		if strings.HasPrefix(lower, prefix) {
+			return common.ContentTypeHTML
+		}
+	}
+	if strings.HasPrefix(lower, "<?xml") {
+		if strings.Contains(strings.ToLower(string(head)), "<html") {
+			return common.ContentTypeHTML
+		}
+		return common.ContentTypeXML
+	}
+
+	if head[0] == '{' || head[0] == '[' {
+		rest := bytes.TrimLeft(head[1:], " \t\r\n")
+		if len(rest) == 0 || bytes.IndexByte([]byte(`"{[]}-0123456789tfn`), rest[0]) >= 0 {
+			return common.ContentTypeJSON
+		}
+	}
+
+	return ""
+}
+
+/*
+detectZipHead walks the local file headers found in head. EPUB and ODF documents store their type
+uncompressed in a mimetype entry that comes first, OOXML documents are recognized by their main part
+if it's listed within head after [Content_Types].xml.
+*/
+func detectZipHead(head []byte) string {
+	var contentTypes bool
+	for off := 0; off+30 <= len(head) && bytes.Equal(head[off:off+4], zipSignature); {
+		var (
+			flags      = binary.LittleEndian.Uint16(head[off+6:])
+			method     = binary.LittleEndian.Uint16(head[off+8:])
+			size       = int(binary.LittleEndian.Uint32(head[off+18:]))
+			nameLen    = int(binary.LittleEndian.Uint16(head[off+26:]))
+			extraLen   = int(binary.LittleEndian.Uint16(head[off+28:]))
+			nameStart  = off + 30
+			dataStart  = nameStart + nameLen + extraLen
+			hasPayload = flags&0x08 == 0
+		)
+		if nameStart+nameLen > len(head) {
+			break
+		}
+
+		name := string(head[nameStart : nameStart+nameLen])
+		if name == "mimetype" && method == zip.Store && hasPayload && dataStart+size <= len(head) {
+			return strings.TrimSpace(string(head[dataStart : dataStart+size]))
+		}
+		if name == "[Content_Types].xml" {
+			contentTypes = true
+		} else if contentType := ooxmlPart(name); contentTypes && contentType != "" {
+			return contentType
+		}
+
+		// without a size the next header can't be found
+		if !hasPayload {
+			break
+		}
+		off = dataStart + size
+	}
+
+	return ContentTypeZip
+}
+
+// ooxmlPart returns the content type of the OOXML document name is the main part of.
+func ooxmlPart(name string) string {
+	switch {
+	case strings.HasPrefix(name, "word/"):
+		return common.ContentTypeDocx
+	case strings.HasPrefix(name, "ppt/"):
+		return common.ContentTypePptx
+	case strings.HasPrefix(name, "xl/"):
+		return ContentTypeXlsx
+	}
+	return ""
+}
+
+/*
+DetectZip returns the content type of a ZIP archive by looking at its entries, it's the fallback for
+when Detect returns ContentTypeZip. Archives that aren't a known document format are ContentTypeZip.
+*/
+func DetectZip(r io.ReaderAt, size int64) string {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return ""
+	}
+
+	var contentTypes bool
+	for _, f := range zr.File {
+		switch {
+		case f.Name == "mimetype":
+			if rc, err := f.Open(); err == nil {
+				mimetype, _ := io.ReadAll(io.LimitReader(rc, 256))
+				rc.Close()
+				if mt := strings.TrimSpace(string(mimetype)); mt != "" {
+					return mt
+				}
+			}
+		case f.Name == "[Content_Types].xml":
+			contentTypes = true
+		}
+	}
+
+	if contentTypes {
+		for _, f := range zr.File {
+			if contentType := ooxmlPart(f.Name); contentType != "" {
+				return contentType
+			}
+		}
+	}
+
+	return ContentTypeZip
+}
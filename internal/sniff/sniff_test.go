@@ -0,0 +1,169 @@
+package sniff
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"hash/crc32"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+type entry struct {
+	name, content string
+	store         bool
+}
+
+func makeZip(t *testing.T, entries ...entry) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		var (
+			w   io.Writer
+			err error
+		)
+		if e.store {
+			// written raw so the sizes are in the local header, as archivers other than Go's do
+			w, err = zw.CreateRaw(&zip.FileHeader{
+				Name:               e.name,
+				Method:             zip.Store,
+				CRC32:              crc32.ChecksumIEEE([]byte(e.content)),
+				CompressedSize64:   uint64(len(e.content)),
+				UncompressedSize64: uint64(len(e.content)),
+			})
+		} else {
+			w, err = zw.CreateHeader(&zip.FileHeader{Name: e.name, Method: zip.Deflate})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeTar(t *testing.T, name string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()[:512]
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{name: "pdf", head: []byte("%PDF-1.7\n%âãÏÓ"), want: common.ContentTypePDF},
+		{name: "wav", head: []byte("RIFF\x24\x08\x00\x00WAVEfmt "), want: ContentTypeWAV},
+		{name: "flac", head: []byte("fLaC\x00\x00\x00\x22"), want: ContentTypeFLAC},
+		{name: "mp3 with id3", head: []byte("ID3\x04\x00\x00"), want: ContentTypeMP3},
+		{name: "mp3 frame", head: []byte{0xFF, 0xFB, 0x90, 0x64}, want: ContentTypeMP3},
+		{name: "html", head: []byte("\xEF\xBB\xBF\n  <!DOCTYPE html><html>"), want: common.ContentTypeHTML},
+		{name: "xhtml", head: []byte(`<?xml version="1.0"?><html xmlns="http://www.w3.org/1999/xhtml">`), want: common.ContentTypeHTML},
+		{name: "xml", head: []byte(`<?xml version="1.0"?><feed>`), want: common.ContentTypeXML},
+		{name: "json object", head: []byte(`  {"key": "value"}`), want: common.ContentTypeJSON},
+		{name: "json array", head: []byte("[\n  1, 2"), want: common.ContentTypeJSON},
+		{name: "gzip", head: []byte{0x1F, 0x8B, 0x08, 0x00}, want: ContentTypeGzip},
+		{name: "tar", head: makeTar(t, "docs/report.txt"), want: ContentTypeTar},
+		{name: "markdown link", head: []byte("[chew](https://github.com/mmatongo/chew)"), want: ""},
+		{name: "plain text", head: []byte("just some text"), want: ""},
+		{name: "empty", head: nil, want: ""},
+		{
+			name: "epub",
+			head: makeZip(t, entry{name: "mimetype", content: common.ContentTypeEPUB, store: true}, entry{name: "META-INF/container.xml"}),
+			want: common.ContentTypeEPUB,
+		},
+		{
+			name: "odf",
+			head: makeZip(t, entry{name: "mimetype", content: "application/vnd.oasis.opendocument.text", store: true}),
+			want: "application/vnd.oasis.opendocument.text",
+		},
+		{
+			name: "docx",
+			head: makeZip(t, entry{name: "[Content_Types].xml", content: "<Types/>", store: true}, entry{name: "word/document.xml"}),
+			want: common.ContentTypeDocx,
+		},
+		{
+			name: "plain zip",
+			head: makeZip(t, entry{name: "word/notes.txt"}),
+			want: ContentTypeZip,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.head); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectZip(t *testing.T) {
+	// incompressible enough to push the main part out of the head
+	types := "<Types>" + strings.Repeat(`<Override PartName="/x" ContentType="y"/>`, 10) + "</Types>"
+	filler := make([]byte, 2048)
+	for i := range filler {
+		filler[i] = byte(i * 7919 % 251)
+	}
+
+	tests := []struct {
+		name    string
+		entries []entry
+		want    string
+	}{
+		{
+			name:    "pptx",
+			entries: []entry{{name: "[Content_Types].xml", content: types}, {name: "docProps/thumbnail.jpeg", content: string(filler)}, {name: "ppt/presentation.xml"}},
+			want:    common.ContentTypePptx,
+		},
+		{
+			name:    "xlsx",
+			entries: []entry{{name: "[Content_Types].xml", content: types}, {name: "xl/workbook.xml"}},
+			want:    ContentTypeXlsx,
+		},
+		{
+			name:    "epub without a leading mimetype",
+			entries: []entry{{name: "META-INF/container.xml"}, {name: "mimetype", content: common.ContentTypeEPUB}},
+			want:    common.ContentTypeEPUB,
+		},
+		{
+			name:    "plain zip",
+			entries: []entry{{name: "readme.txt", content: "hello"}},
+			want:    ContentTypeZip,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := makeZip(t, tt.entries...)
+			if got := DetectZip(bytes.NewReader(data), int64(len(data))); got != tt.want {
+				t.Errorf("DetectZip() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := DetectZip(bytes.NewReader([]byte("not a zip")), 9); got != "" {
+		t.Errorf("DetectZip() of garbage = %q, want \"\"", got)
+	}
+}
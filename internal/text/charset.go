@@ -0,0 +1,177 @@
+package text
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"regexp"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// charsetSniffLen is how much of the content is looked at for a byte order mark or a declared charset.
+const charsetSniffLen = 1024
+
+var (
+	xmlDeclCharset  = regexp.MustCompile(`^<\?xml[^>]*\sencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+	metaCharset     = regexp.MustCompile(`(?i)<meta[^>]*?charset\s*=\s*["']?\s*([A-Za-z0-9._:-]+)`)
+	utf8BOM         = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM      = []byte{0xFF, 0xFE}
+	utf16BEBOM      = []byte{0xFE, 0xFF}
+	guessedCharsets = []encoding.Encoding{japanese.ShiftJIS, japanese.EUCJP}
+)
+
+/*
+NewUTF8Reader returns a reader with the content of r transcoded to UTF-8, the processors assume their
+input is UTF-8. The encoding is taken from, in order, a byte order mark, the charset parameter of
+contentType, an XML declaration or a <meta> charset, and is otherwise guessed from the bytes: content
+that's valid UTF-8 is left alone, then Shift_JIS and EUC-JP are tried before falling back to
+windows-1252. Undeclared content that starts out as valid UTF-8 is read in full before it's guessed,
+legacy files such as CSV exports often have nothing but ASCII until well into their rows.
+*/
+func NewUTF8Reader(r io.Reader, contentType string) io.Reader {
+	br := bufio.NewReaderSize(r, charsetSniffLen)
+	head, err := br.Peek(charsetSniffLen)
+
+	enc, declared := declaredEncoding(head, contentType)
+	if !declared {
+		if err == nil && utf8.Valid(completeRunes(head)) {
+			return &guessingReader{r: br}
+		}
+		enc = guessEncoding(head)
+	}
+	return decode(br, enc)
+}
+
+// decode returns a reader transcoding r from enc to UTF-8, r itself if enc is nil.
+func decode(r io.Reader, enc encoding.Encoding) io.Reader {
+	if enc == nil {
+		return r
+	}
+	return transform.NewReader(r, enc.NewDecoder())
+}
+
+// guessingReader reads all of r on the first read and guesses its encoding from the whole of it.
+type guessingReader struct {
+	r       io.Reader
+	decoded io.Reader
+}
+
+func (g *guessingReader) Read(p []byte) (int, error) {
+	if g.decoded == nil {
+		content, err := io.ReadAll(g.r)
+		if err != nil {
+			return 0, err
+		}
+		g.decoded = decode(bytes.NewReader(content), guessEncoding(content))
+	}
+	return g.decoded.Read(p)
+}
+
+/*
+declaredEncoding returns the encoding of the content starting with head if it has a BOM or a declared
+charset, and reports whether it has. The encoding is nil if it's UTF-8 without a BOM.
+*/
+func declaredEncoding(head []byte, contentType string) (encoding.Encoding, bool) {
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		return unicode.UTF8BOM, true
+	case bytes.HasPrefix(head, utf16LEBOM):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), true
+	case bytes.HasPrefix(head, utf16BEBOM):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM), true
+	}
+
+	declared := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		declared = params["charset"]
+	}
+	if declared == "" {
+		if m := xmlDeclCharset.FindSubmatch(head); m != nil {
+			declared = string(m[1])
+		} else if m := metaCharset.FindSubmatch(head); m != nil {
+			declared = string(m[1])
+		}
+	}
+	if declared != "" {
+		if enc, name := charset.Lookup(declared); enc != nil {
+			if name == "utf-8" {
+				return nil, true
+			}
+			return enc, true
+		}
+	}
+
+	return nil, false
+}
+
+// completeRunes drops the last character of b if it was cut in half at the end.
+func completeRunes(b []byte) []byte {
+	for i := len(b) - 1; i >= 0 && i > len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				return b[:i]
+			}
+			break
+		}
+	}
+	return b
+}
+
+/*
+guessEncoding guesses the encoding of undeclared content, nil means UTF-8. Shift_JIS and EUC-JP are
+only picked for content that decodes without errors into text that looks Japanese, see minKanaShare.
+*/
+func guessEncoding(head []byte) encoding.Encoding {
+	// a character may have been cut in half at the end
+	if utf8.Valid(completeRunes(head)) {
+		return nil
+	}
+
+	for _, enc := range guessedCharsets {
+		out, err := enc.NewDecoder().Bytes(head)
+		if err != nil {
+			continue
+		}
+		// same as above, the last character may be incomplete
+		out = bytes.TrimSuffix(out, []byte(string(utf8.RuneError)))
+		if !bytes.ContainsRune(out, utf8.RuneError) && kanaShare(out) >= minKanaShare {
+			return enc
+		}
+	}
+
+	return charmap.Windows1252
+}
+
+/*
+minKanaShare is the share of the characters outside ASCII that have to be hiragana or katakana for
+content to be taken for Japanese. Most accented Latin letters in windows-1252 are also valid Shift_JIS
+lead bytes, so European text decodes as Shift_JIS without errors too, but into kanji and halfwidth
+katakana, where Japanese text is rarely without kana.
+*/
+const minKanaShare = 0.2
+
+// kanaShare returns the share of the characters outside ASCII in s that are hiragana or katakana.
+func kanaShare(s []byte) float64 {
+	var nonASCII, kana int
+	for _, r := range string(s) {
+		if r < utf8.RuneSelf {
+			continue
+		}
+		nonASCII++
+		if r >= 0x3040 && r <= 0x30FF {
+			kana++
+		}
+	}
+	if nonASCII == 0 {
+		return 0
+	}
+	return float64(kana) / float64(nonASCII)
+}
@@ -0,0 +1,111 @@
+package text
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) string {
+	t.Helper()
+	out, err := enc.NewEncoder().String(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestNewUTF8Reader(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		contentType string
+		want        string
+	}{
+		{
+			name:    "utf-8",
+			content: "naïve café",
+			want:    "naïve café",
+		},
+		{
+			name:    "utf-8 bom",
+			content: "\xEF\xBB\xBFplain",
+			want:    "plain",
+		},
+		{
+			name:    "utf-16 bom",
+			content: encode(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), "wide"),
+			want:    "wide",
+		},
+		{
+			name:        "content type charset",
+			content:     encode(t, charmap.Windows1252, "café,crème"),
+			contentType: "text/csv; charset=windows-1252",
+			want:        "café,crème",
+		},
+		{
+			name:        "content type wins over meta",
+			content:     encode(t, charmap.ISO8859_1, `<meta charset="shift_jis"><p>déjà</p>`),
+			contentType: "text/html; charset=iso-8859-1",
+			want:        `<meta charset="shift_jis"><p>déjà</p>`,
+		},
+		{
+			name:        "meta charset",
+			content:     encode(t, japanese.ShiftJIS, `<html><head><meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"></head><p>日本語</p>`),
+			contentType: "text/html",
+			want:        `<html><head><meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"></head><p>日本語</p>`,
+		},
+		{
+			name:    "xml declaration",
+			content: encode(t, japanese.EUCJP, `<?xml version="1.0" encoding="EUC-JP"?><title>東京</title>`),
+			want:    `<?xml version="1.0" encoding="EUC-JP"?><title>東京</title>`,
+		},
+		{
+			name:    "guessed shift_jis",
+			content: encode(t, japanese.ShiftJIS, "こんにちは、世界"),
+			want:    "こんにちは、世界",
+		},
+		{
+			name:    "guessed euc-jp",
+			content: encode(t, japanese.EUCJP, "東京都は日本の首都です"),
+			want:    "東京都は日本の首都です",
+		},
+		{
+			name:        "windows-1252 that is valid shift_jis",
+			content:     encode(t, charmap.Windows1252, "résumé"),
+			contentType: "text/plain",
+			want:        "résumé",
+		},
+		{
+			name:    "guessed windows-1252",
+			content: encode(t, charmap.Windows1252, "“quoted” café"),
+			want:    "“quoted” café",
+		},
+		{
+			name:    "windows-1252 after an ascii head",
+			content: strings.Repeat("name,city\n", charsetSniffLen) + encode(t, charmap.Windows1252, "Zoë,Málaga"),
+			want:    strings.Repeat("name,city\n", charsetSniffLen) + "Zoë,Málaga",
+		},
+		{
+			name:    "multibyte character cut by the sniff length",
+			content: strings.Repeat("a", charsetSniffLen-1) + "é",
+			want:    strings.Repeat("a", charsetSniffLen-1) + "é",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := io.ReadAll(NewUTF8Reader(strings.NewReader(tt.content), tt.contentType))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("NewUTF8Reader() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
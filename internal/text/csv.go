@@ -10,17 +10,29 @@ import (
 
 func ProcessCSV(r io.Reader, url string) ([]common.Chunk, error) {
 	csvReader := csv.NewReader(r)
-	var records [][]string
-	var err error
 
-	records, err = csvReader.ReadAll()
-	if err != nil {
-		return nil, err
-	}
+	var (
+		chunks []common.Chunk
+		offset int64
+	)
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
 
-	var chunks []common.Chunk
-	for _, record := range records {
-		chunks = append(chunks, common.Chunk{Content: strings.Join(record, ", "), Source: url})
+		chunks = append(chunks, common.Chunk{
+			Content: strings.Join(record, ", "),
+			Source:  url,
+			Metadata: common.Metadata{
+				Index:      len(chunks),
+				ByteOffset: offset,
+			},
+		})
+		offset = csvReader.InputOffset()
 	}
 
 	return chunks, nil
@@ -48,8 +48,15 @@ func TestProcessCSV(t *testing.T) {
 				url: "https://example.com/quoted.csv",
 			},
 			want: []common.Chunk{
-				{Content: "header 1, header 2", Source: "https://example.com/quoted.csv"},
-				{Content: "value, with comma, value2", Source: "https://example.com/quoted.csv"},
+				{
+					Content: "header 1, header 2",
+					Source:  "https://example.com/quoted.csv",
+				},
+				{
+					Content:  "value, with comma, value2",
+					Source:   "https://example.com/quoted.csv",
+					Metadata: common.Metadata{Index: 1, ByteOffset: 22},
+				},
 			},
 			wantErr: false,
 		},
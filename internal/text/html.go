@@ -3,33 +3,166 @@ package text
 import (
 	"fmt"
 	"io"
+	"maps"
+	"slices"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/mmatongo/chew/v1/internal/common"
+	"github.com/mmatongo/chew/v1/internal/markdown"
 )
 
 func ProcessHTML(r io.Reader, url string) ([]common.Chunk, error) {
-	doc, err := goquery.NewDocumentFromReader(r)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse HTML: %w", err)
-	}
+	return NewHTMLProcessor(common.HTMLOptions{})(r, url)
+}
+
+// textElements are the elements extracted as plain text.
+const textElements = "p, h1, h2, h3, h4, h5, h6, li, table"
+
+/*
+NewHTMLProcessor returns an HTML processor configured by opts. With the text format every paragraph,
+heading, list item and table becomes a chunk of plain text, with the markdown format every block (heading,
+paragraph, whole list, code block, blockquote or table) becomes a chunk of markdown.
+
+Only the parts of the page matched by the include selectors are extracted, after removing
+whatever is matched by the exclude selectors, see SelectContent.
+
+The metadata of the page (title, description, canonical URL, Open Graph tags and JSON-LD) is attached
+to every chunk, and with opts.DocumentHeader it's also rendered as a leading chunk.
+*/
+func NewHTMLProcessor(opts common.HTMLOptions) func(io.Reader, string) ([]common.Chunk, error) {
+	return func(r io.Reader, url string) ([]common.Chunk, error) {
+		doc, err := goquery.NewDocumentFromReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+
+		var (
+			chunks   []common.Chunk
+			headings []string
+			page     = pageMetadata(doc, url)
+		)
+
+		add := func(content string, level int, heading string) {
+			if level > 0 {
+				headings = setHeading(headings, level, heading)
+			}
 
-	var chunks []common.Chunk
-	/*
-		We're only interested in the text content of the HTML document
-		so we're going to ignore the tags that don't contain useful text.
-		This is a very naive approach and might not work for all HTML documents unfortunately
-	*/
+			// every chunk gets its own copy, changing the metadata of one mustn't change the others
+			meta := page
+			meta.OpenGraph = maps.Clone(page.OpenGraph)
+			meta.JSONLD = slices.Clone(page.JSONLD)
+			meta.Section = sectionPath(headings)
+			meta.Index = len(chunks)
 
-	doc.Find("nav, header, footer").Remove()
+			chunks = append(chunks, common.Chunk{
+				Content:  content,
+				Source:   url,
+				Metadata: meta,
+			})
+		}
+
+		if opts.DocumentHeader {
+			if header := headerContent(page); header != "" {
+				add(header, 0, "")
+			}
+		}
+
+		content, err := SelectContent(doc, ResolveSelectors(opts, url))
+		if err != nil {
+			return nil, err
+		}
 
-	doc.Find("p, h1, h2, h3, h4, h5, h6, li").Each(func(_ int, s *goquery.Selection) {
-		text := strings.TrimSpace(s.Text())
-		if text != "" {
-			chunks = append(chunks, common.Chunk{Content: text, Source: url})
+		if opts.Format == common.FormatMarkdown {
+			for _, block := range markdown.Blocks(content, markdownOptions(opts)) {
+				add(block.Markdown, block.Heading, block.Text)
+			}
+			return chunks, nil
 		}
-	})
 
-	return chunks, nil
+		/*
+			We're only interested in the text content of the HTML document
+			so we're going to ignore the tags that don't contain useful text.
+			This is a very naive approach and might not work for all HTML documents unfortunately
+		*/
+		content.Each(func(_ int, root *goquery.Selection) {
+			elements := root.Find(textElements)
+			if root.Is(textElements) {
+				elements = root
+			}
+
+			elements.Each(func(_ int, s *goquery.Selection) {
+				if goquery.NodeName(s) == "table" {
+					for _, table := range renderTable(s, opts.Tables) {
+						add(table, 0, "")
+					}
+					return
+				}
+
+				// the content of tables is part of the table's chunks
+				if s.ParentsFiltered("table").Length() > 0 {
+					return
+				}
+
+				text := strings.TrimSpace(s.Text())
+				if text == "" {
+					return
+				}
+				add(text, headingLevel(goquery.NodeName(s)), text)
+			})
+		})
+
+		return chunks, nil
+	}
+}
+
+/*
+renderTable renders a table either as a single GFM table or as one "header: value" record per row,
+the cells are kept as plain text.
+*/
+func renderTable(s *goquery.Selection, format common.TableFormat) []string {
+	table := markdown.ParseTable(s.Nodes[0], markdown.PlainCell)
+	if format == common.TableRows {
+		return table.Records()
+	}
+	if md := table.Markdown(); md != "" {
+		return []string{md}
+	}
+	return nil
+}
+
+func markdownOptions(opts common.HTMLOptions) markdown.Options {
+	return markdown.Options{TableRows: opts.Tables == common.TableRows}
+}
+
+// headingLevel returns the level of a h1-h6 element name, or 0 for anything else.
+func headingLevel(name string) int {
+	if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+		return int(name[1] - '0')
+	}
+	return 0
+}
+
+/*
+setHeading records a heading in the heading path, the path is indexed by level so
+everything below the new heading is dropped since it belonged to the previous section.
+*/
+func setHeading(headings []string, level int, text string) []string {
+	for len(headings) < level {
+		headings = append(headings, "")
+	}
+	headings = headings[:level]
+	headings[level-1] = text
+	return headings
+}
+
+// sectionPath returns the non-empty headings leading up to the current position.
+func sectionPath(headings []string) []string {
+	var path []string
+	for _, h := range headings {
+		if h != "" {
+			path = append(path, h)
+		}
+	}
+	return path
 }
@@ -0,0 +1,120 @@
+package text
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+/*
+pageMetadata extracts the document level metadata of an HTML page, i.e. its title, description,
+canonical URL, language, Open Graph tags and JSON-LD blocks, each chunk of the page gets a copy of it.
+*/
+func pageMetadata(doc *goquery.Document, pageURL string) common.Metadata {
+	meta := common.Metadata{
+		Title: strings.TrimSpace(doc.Find("title").First().Text()),
+	}
+
+	if lang, ok := doc.Find("html").First().Attr("lang"); ok {
+		meta.Language = strings.TrimSpace(lang)
+	}
+
+	doc.Find("meta").Each(func(_ int, s *goquery.Selection) {
+		content, ok := s.Attr("content")
+		if !ok {
+			return
+		}
+		content = strings.TrimSpace(content)
+
+		name := strings.ToLower(strings.TrimSpace(s.AttrOr("name", "")))
+		property := strings.ToLower(strings.TrimSpace(s.AttrOr("property", "")))
+
+		switch {
+		case name == "description" && meta.Description == "":
+			meta.Description = content
+		case strings.HasPrefix(property, "og:"):
+			if meta.OpenGraph == nil {
+				meta.OpenGraph = make(map[string]string)
+			}
+			// the first value wins for repeated properties such as og:image
+			if _, seen := meta.OpenGraph[property]; !seen {
+				meta.OpenGraph[property] = content
+			}
+		}
+	})
+
+	if meta.Title == "" {
+		meta.Title = meta.OpenGraph["og:title"]
+	}
+	if meta.Description == "" {
+		meta.Description = meta.OpenGraph["og:description"]
+	}
+
+	doc.Find("link[rel]").EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		if !hasToken(s.AttrOr("rel", ""), "canonical") {
+			return true
+		}
+		meta.Canonical = resolveURL(pageURL, s.AttrOr("href", ""))
+		return false
+	})
+
+	doc.Find("script[type]").Each(func(_ int, s *goquery.Selection) {
+		// the type is a media type, it may come in any case and with parameters
+		if mt, _, err := mime.ParseMediaType(s.AttrOr("type", "")); err != nil || mt != "application/ld+json" {
+			return
+		}
+
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(strings.TrimSpace(s.Text()))); err != nil {
+			// broken JSON-LD is common enough that it shouldn't fail the whole page
+			return
+		}
+		meta.JSONLD = append(meta.JSONLD, json.RawMessage(buf.Bytes()))
+	})
+
+	return meta
+}
+
+// headerContent renders the document level metadata as the content of a leading chunk.
+func headerContent(meta common.Metadata) string {
+	var lines []string
+	for _, field := range []struct{ key, value string }{
+		{"Title", meta.Title},
+		{"Description", meta.Description},
+		{"URL", meta.Canonical},
+		{"Language", meta.Language},
+	} {
+		if field.value != "" {
+			lines = append(lines, field.key+": "+field.value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func hasToken(list, token string) bool {
+	for _, t := range strings.Fields(list) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveURL resolves ref against base, ref is returned as is if either can't be parsed.
+func resolveURL(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	b, err := url.Parse(base)
+	if err != nil || ref == "" {
+		return ref
+	}
+	r, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
@@ -1,6 +1,7 @@
 package text
 
 import (
+	"encoding/json"
 	"io"
 	"reflect"
 	"strings"
@@ -43,10 +44,19 @@ func TestProcessHTML(t *testing.T) {
 				{
 					Content: "Test content",
 					Source:  "https://example.com/page.html",
+					Metadata: common.Metadata{
+						Title:   "Test HTML",
+						Section: []string{"Test content"},
+					},
 				},
 				{
 					Content: "This is a test paragraph.",
 					Source:  "https://example.com/page.html",
+					Metadata: common.Metadata{
+						Title:   "Test HTML",
+						Section: []string{"Test content"},
+						Index:   1,
+					},
 				},
 			},
 			wantErr: false,
@@ -83,3 +93,208 @@ func TestProcessHTML(t *testing.T) {
 		})
 	}
 }
+
+func TestProcessHTML_Metadata(t *testing.T) {
+	html := `
+		<html lang="en">
+		<head><title>Guide</title></head>
+		<body>
+			<h1>Install</h1>
+			<p>Intro.</p>
+			<h2>Linux</h2>
+			<p>Use apt.</p>
+			<h2>macOS</h2>
+			<p>Use brew.</p>
+			<h1>Usage</h1>
+			<li>Run it.</li>
+		</body>
+		</html>`
+
+	got, err := ProcessHTML(strings.NewReader(html), "https://example.com/guide")
+	if err != nil {
+		t.Fatalf("ProcessHTML() error = %v", err)
+	}
+
+	want := map[string][]string{
+		"Install":   {"Install"},
+		"Intro.":    {"Install"},
+		"Linux":     {"Install", "Linux"},
+		"Use apt.":  {"Install", "Linux"},
+		"macOS":     {"Install", "macOS"},
+		"Use brew.": {"Install", "macOS"},
+		"Usage":     {"Usage"},
+		"Run it.":   {"Usage"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ProcessHTML() returned %d chunks, want %d", len(got), len(want))
+	}
+	for i, chunk := range got {
+		if !reflect.DeepEqual(chunk.Metadata.Section, want[chunk.Content]) {
+			t.Errorf("chunk %q section = %v, want %v", chunk.Content, chunk.Metadata.Section, want[chunk.Content])
+		}
+		if chunk.Metadata.Index != i || chunk.Metadata.Title != "Guide" || chunk.Metadata.Language != "en" {
+			t.Errorf("chunk %q metadata = %+v", chunk.Content, chunk.Metadata)
+		}
+	}
+}
+
+func TestProcessHTML_Markdown(t *testing.T) {
+	html := `
+		<html>
+		<body>
+			<nav><a href="/">Home</a></nav>
+			<h1>Install</h1>
+			<p>Get the <a href="https://example.com/dl">latest release</a>.</p>
+			<pre><code class="language-sh">make install</code></pre>
+			<h2>Options</h2>
+			<ul><li>fast</li><li>safe</li></ul>
+		</body>
+		</html>`
+
+	process := NewHTMLProcessor(common.HTMLOptions{Format: common.FormatMarkdown})
+	got, err := process(strings.NewReader(html), "https://example.com/install")
+	if err != nil {
+		t.Fatalf("ProcessHTML() error = %v", err)
+	}
+
+	want := []struct {
+		content string
+		section []string
+	}{
+		{"# Install", []string{"Install"}},
+		{"Get the [latest release](https://example.com/dl).", []string{"Install"}},
+		{"```sh\nmake install\n```", []string{"Install"}},
+		{"## Options", []string{"Install", "Options"}},
+		{"- fast\n- safe", []string{"Install", "Options"}},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("ProcessHTML() returned %d chunks, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		if got[i].Content != w.content {
+			t.Errorf("chunk %d content = %q, want %q", i, got[i].Content, w.content)
+		}
+		if !reflect.DeepEqual(got[i].Metadata.Section, w.section) {
+			t.Errorf("chunk %d section = %v, want %v", i, got[i].Metadata.Section, w.section)
+		}
+	}
+}
+
+func TestProcessHTML_PageMetadata(t *testing.T) {
+	html := `
+		<html lang="en">
+		<head>
+			<title>Pricing</title>
+			<meta name="description" content="Plans for every team.">
+			<meta property="og:title" content="Pricing | Example">
+			<meta property="og:image" content="https://example.com/a.png">
+			<meta property="og:image" content="https://example.com/b.png">
+			<link rel="canonical" href="/pricing">
+			<script type="application/ld+json">{ "@type": "Product", "name": "Pro" }</script>
+			<script type="application/ld+json">{ broken </script>
+			<script type="Application/LD+JSON; charset=utf-8">{ "@type": "Offer" }</script>
+			<script type="text/javascript">{ "@type": "Script" }</script>
+		</head>
+		<body><p>Pro is $10.</p></body>
+		</html>`
+
+	process := NewHTMLProcessor(common.HTMLOptions{DocumentHeader: true})
+	got, err := process(strings.NewReader(html), "https://example.com/pricing?ref=ad")
+	if err != nil {
+		t.Fatalf("ProcessHTML() error = %v", err)
+	}
+
+	meta := common.Metadata{
+		Title:       "Pricing",
+		Language:    "en",
+		Description: "Plans for every team.",
+		Canonical:   "https://example.com/pricing",
+		OpenGraph:   map[string]string{"og:title": "Pricing | Example", "og:image": "https://example.com/a.png"},
+		JSONLD:      []json.RawMessage{json.RawMessage(`{"@type":"Product","name":"Pro"}`), json.RawMessage(`{"@type":"Offer"}`)},
+	}
+	body := meta
+	body.Index = 1
+
+	want := []common.Chunk{
+		{
+			Content:  "Title: Pricing\nDescription: Plans for every team.\nURL: https://example.com/pricing\nLanguage: en",
+			Source:   "https://example.com/pricing?ref=ad",
+			Metadata: meta,
+		},
+		{Content: "Pro is $10.", Source: "https://example.com/pricing?ref=ad", Metadata: body},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ProcessHTML() = %+v, want %+v", got, want)
+	}
+
+	got[0].Metadata.OpenGraph["og:title"] = "changed"
+	got[0].Metadata.JSONLD[0] = nil
+	if !reflect.DeepEqual(got[1].Metadata, body) {
+		t.Errorf("changing the metadata of one chunk changed another to %+v", got[1].Metadata)
+	}
+}
+
+func TestProcessHTML_Tables(t *testing.T) {
+	html := `
+		<html>
+		<body>
+			<h1>Pricing</h1>
+			<table>
+				<thead><tr><th>Plan</th><th>Price</th></tr></thead>
+				<tbody>
+					<tr><td><p>Pro</p></td><td><a href="/buy">$10</a></td></tr>
+					<tr><td>Free</td><td>$0</td></tr>
+				</tbody>
+			</table>
+			<p>Prices exclude VAT.</p>
+		</body>
+		</html>`
+
+	tests := []struct {
+		name string
+		opts common.HTMLOptions
+		want []string
+	}{
+		{
+			name: "text",
+			opts: common.HTMLOptions{},
+			want: []string{"Pricing", "| Plan | Price |\n| --- | --- |\n| Pro | $10 |\n| Free | $0 |", "Prices exclude VAT."},
+		},
+		{
+			name: "text rows",
+			opts: common.HTMLOptions{Tables: common.TableRows},
+			want: []string{"Pricing", "Plan: Pro\nPrice: $10", "Plan: Free\nPrice: $0", "Prices exclude VAT."},
+		},
+		{
+			name: "markdown",
+			opts: common.HTMLOptions{Format: common.FormatMarkdown},
+			want: []string{"# Pricing", "| Plan | Price |\n| --- | --- |\n| Pro | [$10](/buy) |\n| Free | $0 |", "Prices exclude VAT."},
+		},
+		{
+			name: "markdown rows",
+			opts: common.HTMLOptions{Format: common.FormatMarkdown, Tables: common.TableRows},
+			want: []string{"# Pricing", "Plan: Pro\nPrice: [$10](/buy)", "Plan: Free\nPrice: $0", "Prices exclude VAT."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := NewHTMLProcessor(tt.opts)(strings.NewReader(html), "https://example.com/pricing")
+			if err != nil {
+				t.Fatalf("ProcessHTML() error = %v", err)
+			}
+
+			var got []string
+			for _, c := range chunks {
+				got = append(got, c.Content)
+				if !reflect.DeepEqual(c.Metadata.Section, []string{"Pricing"}) {
+					t.Errorf("chunk %q section = %v", c.Content, c.Metadata.Section)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ProcessHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package text
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Link is a link found on an HTML page, NoFollow is set for links marked rel="nofollow".
+type Link struct {
+	URL      string
+	NoFollow bool
+}
+
+/*
+Page holds what's needed to crawl an HTML page, its links and the content of its named meta tags
+keyed by their lowercased name (i.e. "robots" or "googlebot"). Repeated tags are joined with ", ".
+*/
+type Page struct {
+	Links []Link
+	Meta  map[string]string
+}
+
+/*
+ParsePage parses an HTML page for crawling. Links are resolved against the page's <base href> if it
+has one and pageURL otherwise, only http and https links are kept, each once, in the order they appear.
+*/
+func ParsePage(r io.Reader, pageURL string) (*Page, error) {
+	doc, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing page URL: %w", err)
+	}
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+
+	page := &Page{Meta: make(map[string]string)}
+
+	seen := make(map[string]bool)
+	doc.Find("a[href]").Each(func(_ int, s *goquery.Selection) {
+		u, err := base.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return
+		}
+		u.Fragment = ""
+
+		link := u.String()
+		if !seen[link] {
+			seen[link] = true
+			page.Links = append(page.Links, Link{URL: link, NoFollow: hasToken(s.AttrOr("rel", ""), "nofollow")})
+		}
+	})
+
+	doc.Find("meta[name][content]").Each(func(_ int, s *goquery.Selection) {
+		name := strings.ToLower(strings.TrimSpace(s.AttrOr("name", "")))
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		if prev, ok := page.Meta[name]; ok {
+			content = prev + ", " + content
+		}
+		page.Meta[name] = content
+	})
+
+	return page, nil
+}
@@ -0,0 +1,69 @@
+package text
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePage_Links(t *testing.T) {
+	tests := []struct {
+		name    string
+		html    string
+		pageURL string
+		want    []Link
+	}{
+		{
+			name:    "relative and absolute",
+			html:    `<a href="/about">About</a> <a href="guide#install">Guide</a> <a href="https://other.org/" rel="external nofollow">Other</a> <a href="/about">Again</a>`,
+			pageURL: "https://example.com/docs/index.html",
+			want: []Link{
+				{URL: "https://example.com/about"},
+				{URL: "https://example.com/docs/guide"},
+				{URL: "https://other.org/", NoFollow: true},
+			},
+		},
+		{
+			name:    "base href",
+			html:    `<head><base href="https://cdn.example.com/v2/"></head><a href="page">Page</a>`,
+			pageURL: "https://example.com/",
+			want:    []Link{{URL: "https://cdn.example.com/v2/page"}},
+		},
+		{
+			name:    "non http links are skipped",
+			html:    `<a href="mailto:me@example.com">Mail</a> <a href="javascript:void(0)">JS</a> <a href="ftp://example.com/f">FTP</a>`,
+			pageURL: "https://example.com/",
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePage(strings.NewReader(tt.html), tt.pageURL)
+			if err != nil {
+				t.Fatalf("ParsePage() error = %v", err)
+			}
+			if !reflect.DeepEqual(got.Links, tt.want) {
+				t.Errorf("ParsePage() links = %v, want %v", got.Links, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePage_Meta(t *testing.T) {
+	html := `<head>
+		<meta name="Robots" content="noindex">
+		<meta name="robots" content="nofollow">
+		<meta name="chewbot" content="none">
+		<meta name="description" content="A page">
+	</head>`
+
+	got, err := ParsePage(strings.NewReader(html), "https://example.com/")
+	if err != nil {
+		t.Fatalf("ParsePage() error = %v", err)
+	}
+
+	want := map[string]string{"robots": "noindex, nofollow", "chewbot": "none", "description": "A page"}
+	if !reflect.DeepEqual(got.Meta, want) {
+		t.Errorf("ParsePage() meta = %v, want %v", got.Meta, want)
+	}
+}
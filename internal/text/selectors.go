@@ -0,0 +1,93 @@
+package text
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/mmatongo/chew/v1/internal/common"
+	"github.com/mmatongo/chew/v1/internal/readability"
+)
+
+// defaultExclude is removed from every page unless other exclude selectors are configured.
+var defaultExclude = []string{"nav", "header", "footer"}
+
+/*
+ResolveSelectors returns opts with the selectors configured for the domain of rawURL applied. Domains
+match their subdomains as well and the most specific one wins, an override only replaces the selectors
+it sets so a domain can have its own include selectors while sharing the global exclude ones.
+*/
+func ResolveSelectors(opts common.HTMLOptions, rawURL string) common.HTMLOptions {
+	u, err := url.Parse(rawURL)
+	if err != nil || len(opts.Domains) == 0 {
+		return opts
+	}
+	host := strings.ToLower(u.Hostname())
+
+	var (
+		best      string
+		selectors common.Selectors
+		found     bool
+	)
+	for domain, s := range opts.Domains {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "."))
+		if domain == "" || (host != domain && !strings.HasSuffix(host, "."+domain)) {
+			continue
+		}
+		if !found || len(domain) > len(best) {
+			best, selectors, found = domain, s, true
+		}
+	}
+
+	if found {
+		if selectors.Include != nil {
+			opts.Include = selectors.Include
+		}
+		if selectors.Exclude != nil {
+			opts.Exclude = selectors.Exclude
+		}
+	}
+	opts.Domains = nil
+
+	return opts
+}
+
+/*
+SelectContent removes everything matched by the exclude selectors of opts from doc and returns what's
+matched by its include selectors, or the whole body when there are none. Matches nested in another
+match are dropped so nothing is extracted twice.
+
+With opts.Readability set only the main content of the selection is returned, see readability.Extract.
+*/
+func SelectContent(doc *goquery.Document, opts common.HTMLOptions) (*goquery.Selection, error) {
+	exclude := opts.Exclude
+	if exclude == nil {
+		exclude = defaultExclude
+	}
+
+	for _, selector := range append(slices.Clone(opts.Include), exclude...) {
+		if _, err := cascadia.Compile(selector); err != nil {
+			return nil, fmt.Errorf("invalid selector %q: %w", selector, err)
+		}
+	}
+
+	for _, selector := range exclude {
+		doc.Find(selector).Remove()
+	}
+
+	sel := doc.Find("body")
+	if len(opts.Include) > 0 {
+		selector := strings.Join(opts.Include, ", ")
+		sel = doc.Find(selector)
+		sel = sel.NotSelection(sel.Find(selector))
+	}
+
+	if opts.Readability {
+		sel = readability.Extract(sel)
+	}
+
+	return sel, nil
+}
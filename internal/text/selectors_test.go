@@ -0,0 +1,114 @@
+package text
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+func TestResolveSelectors(t *testing.T) {
+	opts := common.HTMLOptions{
+		Include: []string{"main"},
+		Exclude: []string{".cookie-banner"},
+		Domains: map[string]common.Selectors{
+			"example.com":      {Exclude: []string{".sidebar"}},
+			"docs.example.com": {Include: []string{"article.main-content"}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		url         string
+		wantInclude []string
+		wantExclude []string
+	}{
+		{name: "no match", url: "https://other.org/page", wantInclude: []string{"main"}, wantExclude: []string{".cookie-banner"}},
+		{name: "domain", url: "https://example.com/page", wantInclude: []string{"main"}, wantExclude: []string{".sidebar"}},
+		{name: "subdomain", url: "https://www.example.com/page", wantInclude: []string{"main"}, wantExclude: []string{".sidebar"}},
+		{name: "most specific domain", url: "https://docs.example.com/guide", wantInclude: []string{"article.main-content"}, wantExclude: []string{".cookie-banner"}},
+		{name: "suffix isn't a subdomain", url: "https://notexample.com", wantInclude: []string{"main"}, wantExclude: []string{".cookie-banner"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ResolveSelectors(opts, tt.url)
+			if !reflect.DeepEqual(got.Include, tt.wantInclude) || !reflect.DeepEqual(got.Exclude, tt.wantExclude) {
+				t.Errorf("ResolveSelectors() = %v %v, want %v %v", got.Include, got.Exclude, tt.wantInclude, tt.wantExclude)
+			}
+		})
+	}
+}
+
+func TestNewHTMLProcessor_Selectors(t *testing.T) {
+	html := `
+		<html>
+		<body>
+			<nav><li>Home</li></nav>
+			<div class="cookie-banner"><p>We use cookies.</p></div>
+			<article class="main-content">
+				<h1>Guide</h1>
+				<p>Read this.</p>
+				<aside class="sidebar"><p>Related</p></aside>
+			</article>
+			<p>Outside.</p>
+		</body>
+		</html>`
+
+	tests := []struct {
+		name    string
+		opts    common.HTMLOptions
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			opts: common.HTMLOptions{},
+			want: []string{"We use cookies.", "Guide", "Read this.", "Related", "Outside."},
+		},
+		{
+			name: "include and exclude",
+			opts: common.HTMLOptions{
+				Include: []string{"article.main-content", "article p"},
+				Exclude: []string{".sidebar"},
+			},
+			want: []string{"Guide", "Read this."},
+		},
+		{
+			name: "markdown",
+			opts: common.HTMLOptions{
+				Format:  common.FormatMarkdown,
+				Exclude: []string{"nav", ".cookie-banner", "aside"},
+			},
+			want: []string{"# Guide", "Read this.", "Outside."},
+		},
+		{
+			name: "domain override",
+			opts: common.HTMLOptions{
+				Domains: map[string]common.Selectors{"example.com": {Include: []string{"article"}}},
+			},
+			want: []string{"Guide", "Read this.", "Related"},
+		},
+		{
+			name:    "invalid selector",
+			opts:    common.HTMLOptions{Include: []string{"article["}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks, err := NewHTMLProcessor(tt.opts)(strings.NewReader(html), "https://docs.example.com/guide")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHTMLProcessor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			var got []string
+			for _, c := range chunks {
+				got = append(got, c.Content)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewHTMLProcessor() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,15 +4,26 @@ import (
 	"bytes"
 	"encoding/xml"
 	"io"
+	"slices"
 
 	"github.com/mmatongo/chew/v1/internal/common"
 )
 
 func ProcessXML(r io.Reader, url string) ([]common.Chunk, error) {
 	decoder := xml.NewDecoder(r)
-	var chunks []common.Chunk
-	var currentElement string
+	// the content has been transcoded to UTF-8 by now, whatever the encoding in its declaration says
+	decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+		return input, nil
+	}
+
+	var (
+		chunks         []common.Chunk
+		currentElement string
+		path           []string
+	)
+
 	for {
+		offset := decoder.InputOffset()
 		t, err := decoder.Token()
 		if err == io.EOF {
 			break
@@ -20,18 +31,30 @@ func ProcessXML(r io.Reader, url string) ([]common.Chunk, error) {
 		if err != nil {
 			return nil, err
 		}
+
 		switch se := t.(type) {
 		case xml.StartElement:
 			currentElement = se.Name.Local
+			path = append(path, se.Name.Local)
+		case xml.EndElement:
+			if len(path) > 0 {
+				path = path[:len(path)-1]
+			}
 		case xml.CharData:
 			content := string(bytes.TrimSpace(se))
 			if content != "" && currentElement != "" {
 				chunks = append(chunks, common.Chunk{
 					Content: content,
 					Source:  url,
+					Metadata: common.Metadata{
+						Section:    slices.Clone(path),
+						Index:      len(chunks),
+						ByteOffset: offset,
+					},
 				})
 			}
 		}
 	}
+
 	return chunks, nil
 }
@@ -29,6 +29,10 @@ func TestProcessXML(t *testing.T) {
 			want: []common.Chunk{{
 				Content: "Test content",
 				Source:  "https://example.com",
+				Metadata: common.Metadata{
+					Section:    []string{"root", "child"},
+					ByteOffset: 13,
+				},
 			}},
 
 			wantErr: false,
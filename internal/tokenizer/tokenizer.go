@@ -0,0 +1,205 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+/*
+pieceRe is the cl100k_base pre-tokenization pattern without the `\s+(?!\S)` alternative,
+Go's regexp package doesn't support lookaheads so that case is handled in pretokenize.
+*/
+var pieceRe = regexp.MustCompile(`^(?:(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+)`)
+
+/*
+BPE is a byte level BPE tokenizer compatible with the tiktoken encodings used by OpenAI models
+(e.g. cl100k_base). The vocabulary isn't bundled, it's loaded from a .tiktoken file.
+*/
+type BPE struct {
+	ranks map[string]int
+}
+
+// LoadFile loads a BPE vocabulary in the tiktoken format from path.
+func LoadFile(path string) (*BPE, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening vocabulary: %w", err)
+	}
+	defer f.Close()
+
+	return Load(f)
+}
+
+/*
+Load reads a BPE vocabulary in the tiktoken format, one token per line made up of the
+base64 encoded bytes of the token followed by its rank.
+*/
+func Load(r io.Reader) (*BPE, error) {
+	ranks := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		token, rank, ok := strings.Cut(text, " ")
+		if !ok {
+			return nil, fmt.Errorf("malformed vocabulary on line %d", line)
+		}
+
+		decoded, err := base64.StdEncoding.DecodeString(token)
+		if err != nil {
+			return nil, fmt.Errorf("decoding token on line %d: %w", line, err)
+		}
+
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, fmt.Errorf("parsing rank on line %d: %w", line, err)
+		}
+
+		ranks[string(decoded)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(ranks) == 0 {
+		return nil, fmt.Errorf("vocabulary is empty")
+	}
+
+	return &BPE{ranks: ranks}, nil
+}
+
+// Encode returns the token ids of text.
+func (b *BPE) Encode(text string) []int {
+	var tokens []int
+	for _, piece := range pretokenize(text) {
+		tokens = append(tokens, b.encodePiece([]byte(piece))...)
+	}
+	return tokens
+}
+
+// Count returns the number of tokens in text.
+func (b *BPE) Count(text string) int {
+	return len(b.Encode(text))
+}
+
+/*
+encodePiece merges the bytes of a piece pairwise, always merging the pair with the lowest rank
+first, until no adjacent pair is in the vocabulary.
+*/
+func (b *BPE) encodePiece(piece []byte) []int {
+	if rank, ok := b.ranks[string(piece)]; ok {
+		return []int{rank}
+	}
+
+	parts := make([][]byte, len(piece))
+	for i := range piece {
+		parts[i] = piece[i : i+1]
+	}
+
+	for len(parts) > 1 {
+		best, bestRank := -1, math.MaxInt
+		for i := 0; i < len(parts)-1; i++ {
+			merged := string(parts[i]) + string(parts[i+1])
+			if rank, ok := b.ranks[merged]; ok && rank < bestRank {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+
+		// parts are adjacent slices of piece so growing one swallows the next
+		parts[best] = parts[best][:len(parts[best])+len(parts[best+1])]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+
+	tokens := make([]int, 0, len(parts))
+	for _, part := range parts {
+		if rank, ok := b.ranks[string(part)]; ok {
+			tokens = append(tokens, rank)
+			continue
+		}
+		// a complete vocabulary has every single byte, this only happens with partial ones
+		for range part {
+			tokens = append(tokens, -1)
+		}
+	}
+	return tokens
+}
+
+/*
+Estimate approximates the token count of text without a vocabulary. It splits text the same way
+the BPE tokenizer does and assumes roughly four characters per token for latin text and a token
+per character for everything else, which is usually within 10-20% for English.
+*/
+type Estimate struct{}
+
+func (Estimate) Count(text string) int {
+	count := 0
+	for _, piece := range pretokenize(text) {
+		piece = strings.TrimPrefix(piece, " ")
+		if piece == "" {
+			count++
+			continue
+		}
+
+		ascii, other := 0, 0
+		for _, r := range piece {
+			if r < utf8.RuneSelf {
+				ascii++
+			} else if !unicode.IsSpace(r) {
+				other++
+			}
+		}
+		count += (ascii+3)/4 + other
+	}
+	return count
+}
+
+// pretokenize splits text into the pieces that are encoded independently by the BPE tokenizer.
+func pretokenize(text string) []string {
+	var pieces []string
+	for len(text) > 0 {
+		match := pieceRe.FindString(text)
+		if match == "" {
+			_, size := utf8.DecodeRuneInString(text)
+			match = text[:size]
+		}
+
+		/*
+			This emulates `\s+(?!\S)`, a run of whitespace followed by something else leaves its
+			last character behind so it can be attached to the next piece, i.e. " world".
+		*/
+		if len(match) < len(text) && isSpace(match) && !strings.ContainsAny(match, "\r\n") {
+			if _, size := utf8.DecodeLastRuneInString(match); len(match) > size {
+				match = match[:len(match)-size]
+			}
+		}
+
+		pieces = append(pieces, match)
+		text = text[len(match):]
+	}
+	return pieces
+}
+
+func isSpace(s string) bool {
+	for _, r := range s {
+		if !unicode.IsSpace(r) {
+			return false
+		}
+	}
+	return true
+}
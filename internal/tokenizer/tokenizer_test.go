@@ -0,0 +1,135 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testVocabulary() string {
+	var b strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, token := range []string{"he", "ll", "llo", " w", " wor", "ld", " world"} {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), 256+i)
+	}
+	return b.String()
+}
+
+func TestBPE_Encode(t *testing.T) {
+	bpe, err := Load(strings.NewReader(testVocabulary()))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		text string
+		want []int
+	}{
+		{
+			name: "merges lowest rank first",
+			text: "hello",
+			want: []int{256, 258},
+		},
+		{
+			name: "whole piece in vocabulary",
+			text: "hello world",
+			want: []int{256, 258, 262},
+		},
+		{
+			name: "unknown pairs stay as bytes",
+			text: "xyz",
+			want: []int{'x', 'y', 'z'},
+		},
+		{
+			name: "empty",
+			text: "",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := bpe.Encode(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Encode() = %v, want %v", got, tt.want)
+			}
+			if n := bpe.Count(tt.text); n != len(tt.want) {
+				t.Errorf("Count() = %v, want %v", n, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{name: "valid", input: "aGk= 0\n\nIQ== 1\n", wantErr: false},
+		{name: "empty", input: "", wantErr: true},
+		{name: "missing rank", input: "aGk=\n", wantErr: true},
+		{name: "invalid base64", input: "!!! 0\n", wantErr: true},
+		{name: "invalid rank", input: "aGk= one\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadFile_Missing(t *testing.T) {
+	if _, err := LoadFile("nonexistent.tiktoken"); err == nil {
+		t.Error("LoadFile() error = nil, want an error")
+	}
+}
+
+func Test_pretokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "Hello world", want: []string{"Hello", " world"}},
+		{text: "Hello   world", want: []string{"Hello", "  ", " world"}},
+		{text: "I'll pay 12345!", want: []string{"I", "'ll", " pay", " ", "123", "45", "!"}},
+		{text: "line\n\nnext  ", want: []string{"line", "\n\n", "next", "  "}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			got := pretokenize(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pretokenize() = %q, want %q", got, tt.want)
+			}
+			if strings.Join(got, "") != tt.text {
+				t.Errorf("pretokenize() lost text: %q", got)
+			}
+		})
+	}
+}
+
+func TestEstimate_Count(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{text: "", want: 0},
+		{text: "Hello world", want: 4},
+		{text: "a b c", want: 3},
+		{text: "日本語", want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := (Estimate{}).Count(tt.text); got != tt.want {
+				t.Errorf("Count() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package chew
+
+import (
+	"context"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// defaultMaxConcurrency is used when Config.MaxConcurrency isn't set.
+const defaultMaxConcurrency = 10
+
+/*
+run processes the URLs with a bounded pool of workers and sends the outcome for each of them on
+the returned channel. The channel is closed once all of them are done or the context is cancelled,
+in which case the URLs that weren't picked up yet are dropped. file:// directories and glob
+patterns are expanded into the files they match first, see FileOptions.
+*/
+func (c *Chew) run(ctx context.Context, urls []string) <-chan sourceResult {
+	var (
+		out = make(chan sourceResult)
+		wg  sync.WaitGroup
+	)
+
+	urls, failed := c.expandSources(urls)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, sr := range failed {
+			select {
+			case out <- sr:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	workers := c.config.MaxConcurrency
+	if workers <= 0 {
+		workers = defaultMaxConcurrency
+	}
+	if workers > len(urls) {
+		workers = len(urls)
+	}
+
+	jobs := make(chan job)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				sr := c.runAcquired(ctx, j.url, fetchInfo{}, time.Now())
+				j.release()
+				select {
+				case out <- sr:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+		c.dispatch(ctx, urls, jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	return out
+}
+
+// job is a URL handed to a worker along with the slot of its host, released once it's processed.
+type job struct {
+	url     string
+	release func()
+}
+
+/*
+dispatch hands the URLs to the workers on jobs, taking the slot of a URL's host before a worker. URLs
+of hosts that are already busy wait in a queue of their own rather than in a worker, so that they
+don't hold up the workers URLs of other hosts could use. The hosts take turns, each URL of a host is
+dispatched in order.
+*/
+func (c *Chew) dispatch(ctx context.Context, urls []string, jobs chan<- job) {
+	var (
+		hosts  []string
+		queues = make(map[string][]string)
+	)
+	for _, url := range urls {
+		host := hostOf(url)
+		if _, ok := queues[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		queues[host] = append(queues[host], url)
+	}
+
+	for len(hosts) > 0 {
+		// taken before the slots are tried, so a slot released in between isn't missed
+		freed := c.hostSlots.released()
+
+		ready := hosts
+		for len(ready) > 0 {
+			var next []string
+			for _, host := range ready {
+				release, ok := c.hostSlots.tryAcquire(host)
+				if !ok {
+					continue
+				}
+
+				select {
+				case jobs <- job{url: queues[host][0], release: release}:
+				case <-ctx.Done():
+					release()
+					return
+				}
+
+				if queues[host] = queues[host][1:]; len(queues[host]) > 0 {
+					next = append(next, host)
+				}
+			}
+			ready = next
+		}
+
+		hosts = slices.DeleteFunc(hosts, func(host string) bool { return len(queues[host]) == 0 })
+		if len(hosts) == 0 {
+			return
+		}
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runOne processes a single URL once a slot for its host is available.
+func (c *Chew) runOne(ctx context.Context, url string, info fetchInfo) sourceResult {
+	start := time.Now()
+
+	release, err := c.hostSlots.acquire(ctx, hostOf(url))
+	if err != nil {
+		return sourceResult{url: url, info: info, err: err, duration: time.Since(start)}
+	}
+	defer release()
+
+	return c.runAcquired(ctx, url, info, start)
+}
+
+// runAcquired processes a single URL whose host slot has already been taken.
+func (c *Chew) runAcquired(ctx context.Context, url string, info fetchInfo, start time.Time) sourceResult {
+	sr := sourceResult{url: url, info: info}
+	sr.chunks, sr.err = c.processSource(ctx, url, &sr.info)
+	sr.duration = time.Since(start)
+	return sr
+}
+
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+/*
+hostSlots limits the number of sources being processed at the same time for a single host.
+It's shared by every call made on a Chew instance, sources without a host (i.e. files) aren't limited.
+*/
+type hostSlots struct {
+	limit int
+	mu    sync.Mutex
+	busy  map[string]int
+	// freed is closed, and replaced, whenever a slot is released
+	freed chan struct{}
+}
+
+func newHostSlots(limit int) *hostSlots {
+	return &hostSlots{
+		limit: limit,
+		busy:  make(map[string]int),
+		freed: make(chan struct{}),
+	}
+}
+
+// acquire waits for a slot for host, the returned func releases it.
+func (h *hostSlots) acquire(ctx context.Context, host string) (func(), error) {
+	for {
+		freed := h.released()
+		if release, ok := h.tryAcquire(host); ok {
+			return release, nil
+		}
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// tryAcquire takes a slot for host if one is free, the returned func releases it.
+func (h *hostSlots) tryAcquire(host string) (func(), bool) {
+	if h == nil || h.limit <= 0 || host == "" {
+		return func() {}, true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.busy[host] >= h.limit {
+		return nil, false
+	}
+	h.busy[host]++
+
+	var once sync.Once
+	return func() { once.Do(func() { h.release(host) }) }, true
+}
+
+func (h *hostSlots) release(host string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.busy[host]--; h.busy[host] <= 0 {
+		delete(h.busy, host)
+	}
+	close(h.freed)
+	h.freed = make(chan struct{})
+}
+
+// released returns a channel that's closed the next time a slot is released, nil if slots are never taken.
+func (h *hostSlots) released() <-chan struct{} {
+	if h == nil || h.limit <= 0 {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.freed
+}
@@ -0,0 +1,205 @@
+package chew
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRun_ConcurrencyLimits(t *testing.T) {
+	var inFlight, peak atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("content"))
+	}))
+	defer server.Close()
+
+	urls := make([]string, 12)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/%d", server.URL, i)
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		wantPeak int32
+	}{
+		{
+			name:     "global limit",
+			config:   Config{MaxConcurrency: 3},
+			wantPeak: 3,
+		},
+		{
+			name:     "per host limit",
+			config:   Config{MaxConcurrency: 8, MaxConcurrencyPerHost: 2},
+			wantPeak: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peak.Store(0)
+
+			tt.config.IgnoreRobotsTxt = true
+			tt.config.RateLimit = time.Microsecond
+			tt.config.RateBurst = len(urls)
+			chew := New(tt.config)
+
+			result, err := chew.ProcessBatch(context.Background(), urls)
+			if err != nil {
+				t.Fatalf("ProcessBatch() error = %v", err)
+			}
+			if len(result.Chunks) != len(urls) {
+				t.Errorf("ProcessBatch() processed %d urls, want %d", len(result.Chunks), len(urls))
+			}
+			if got := peak.Load(); got > tt.wantPeak {
+				t.Errorf("peak concurrency = %d, want at most %d", got, tt.wantPeak)
+			}
+		})
+	}
+}
+
+func TestRun_BusyHostDoesNotHoldUpWorkers(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("slow"))
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte("fast"))
+	}))
+	defer fast.Close()
+
+	// grouped by host, the fast host only comes up once every worker could have been taken by the slow one
+	var urls []string
+	for i := 0; i < 6; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", slow.URL, i))
+	}
+	for i := 0; i < 3; i++ {
+		urls = append(urls, fmt.Sprintf("%s/%d", fast.URL, i))
+	}
+
+	chew := New(Config{
+		IgnoreRobotsTxt:       true,
+		RateLimit:             time.Microsecond,
+		RateBurst:             len(urls),
+		MaxConcurrency:        4,
+		MaxConcurrencyPerHost: 1,
+	})
+
+	start := time.Now()
+	fastDone := 0
+	for sr := range chew.run(context.Background(), urls) {
+		if sr.err != nil {
+			t.Fatalf("run() %s error = %v", sr.url, sr.err)
+		}
+		if hostOf(sr.url) == hostOf(fast.URL) {
+			if fastDone++; fastDone == 3 {
+				if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+					t.Errorf("fast host done after %v, want it done while the slow host is busy", elapsed)
+				}
+			}
+		}
+	}
+	if fastDone != 3 {
+		t.Errorf("run() processed %d urls of the fast host, want 3", fastDone)
+	}
+}
+
+func TestRun_DrainsOnCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(time.Second):
+		}
+	}))
+	defer server.Close()
+
+	chew := New(Config{
+		IgnoreRobotsTxt: true,
+		RateLimit:       time.Microsecond,
+		RateBurst:       100,
+		MaxConcurrency:  2,
+	})
+
+	urls := make([]string, 50)
+	for i := range urls {
+		urls[i] = fmt.Sprintf("%s/%d", server.URL, i)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out := chew.run(ctx, urls)
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("run() did not drain after the context was cancelled")
+	}
+}
+
+func Test_hostSlots(t *testing.T) {
+	slots := newHostSlots(1)
+	ctx := context.Background()
+
+	release, err := slots.acquire(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+
+	if _, err := slots.acquire(ctx, "example.org"); err != nil {
+		t.Errorf("acquire() for another host error = %v", err)
+	}
+
+	if _, err := slots.acquire(ctx, ""); err != nil {
+		t.Errorf("acquire() without a host error = %v", err)
+	}
+
+	timeout, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, err := slots.acquire(timeout, "example.com"); err == nil {
+		t.Error("acquire() on a full host error = nil, want an error")
+	}
+
+	if _, ok := slots.tryAcquire("example.com"); ok {
+		t.Error("tryAcquire() on a full host = true, want false")
+	}
+
+	freed := slots.released()
+	release()
+	release()
+	select {
+	case <-freed:
+	default:
+		t.Error("released() channel wasn't closed by release()")
+	}
+	if _, err := slots.acquire(ctx, "example.com"); err != nil {
+		t.Errorf("acquire() after release error = %v", err)
+	}
+	if _, ok := slots.tryAcquire("example.com"); ok {
+		t.Error("tryAcquire() after releasing twice = true, want the slot released once")
+	}
+}
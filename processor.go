@@ -0,0 +1,360 @@
+package chew
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"sync"
+
+	"github.com/mmatongo/chew/v1/internal/document"
+	"github.com/mmatongo/chew/v1/internal/sniff"
+	"github.com/mmatongo/chew/v1/internal/text"
+	"github.com/mmatongo/chew/v1/internal/utils"
+)
+
+/*
+Processor is the common interface implemented by every content type handler. It receives the
+content of a single source along with the URL (or file path) it came from and returns the chunks
+extracted from it.
+
+A processor that could only process part of the content may return the chunks it has along with an
+error wrapping ErrPartialContent, they're passed on to the caller with the error.
+
+Custom processors can be registered on a Chew instance to add support for new formats or to
+replace one of the built-in ones.
+
+Usage:
+
+	type upperProcessor struct{}
+
+	func (upperProcessor) Process(r io.Reader, source string) ([]chew.Chunk, error) {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, err
+		}
+		return []chew.Chunk{{Content: strings.ToUpper(string(content)), Source: source}}, nil
+	}
+
+	c := chew.New(config)
+	c.Registry().RegisterContentType("text/plain", upperProcessor{}, 0)
+*/
+type Processor interface {
+	Process(r io.Reader, source string) ([]Chunk, error)
+}
+
+// ProcessorFunc allows an ordinary function to be used as a Processor.
+type ProcessorFunc func(r io.Reader, source string) ([]Chunk, error)
+
+func (f ProcessorFunc) Process(r io.Reader, source string) ([]Chunk, error) {
+	return f(r, source)
+}
+
+/*
+Sniffer reports whether the leading bytes of some content belong to the format handled by a
+processor. It's handed at most sniffLen bytes and should never hold on to the slice.
+*/
+type Sniffer func(head []byte) bool
+
+// sniffLen is the number of leading bytes made available to sniffers.
+const sniffLen = 512
+
+type matchKind int
+
+/*
+The order of these matters, when two registrations share the same priority the one with
+the lower kind wins, i.e. an explicit content type beats a file extension which beats a sniffer.
+*/
+const (
+	matchContentType matchKind = iota
+	matchExtension
+	matchSniffer
+)
+
+type registration struct {
+	kind      matchKind
+	key       string
+	sniffer   Sniffer
+	processor Processor
+	priority  int
+	seq       int
+}
+
+/*
+Registry holds the processors known to a Chew instance. Processors can be registered by MIME type,
+by file extension or by a magic-byte Sniffer, each with a priority.
+
+When looking up a processor every registration matching the content is considered and the one with
+the highest priority is used. Ties are broken by the kind of match (content type, then extension,
+then sniffer) and finally by registration order, with the most recent registration winning. This
+means registering a processor with the same key and priority as a built-in one replaces it.
+
+This type is safe for concurrent use.
+*/
+type Registry struct {
+	mu            sync.RWMutex
+	registrations []registration
+	seq           int
+}
+
+// NewRegistry returns an empty registry with none of the built-in processors.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+/*
+RegisterContentType registers a processor for a MIME type such as "text/html". Parameters in
+the content type returned by a server (e.g. "; charset=utf-8") are ignored when matching.
+*/
+func (r *Registry) RegisterContentType(contentType string, p Processor, priority int) {
+	r.register(registration{
+		kind:      matchContentType,
+		key:       mediaType(contentType),
+		processor: p,
+		priority:  priority,
+	})
+}
+
+/*
+RegisterExtension registers a processor for a file extension such as ".md". Extensions are matched
+against the path of the source and are case insensitive.
+*/
+func (r *Registry) RegisterExtension(ext string, p Processor, priority int) {
+	ext = strings.ToLower(ext)
+	if !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	r.register(registration{
+		kind:      matchExtension,
+		key:       ext,
+		processor: p,
+		priority:  priority,
+	})
+}
+
+// RegisterSniffer registers a processor for any content whose leading bytes satisfy s.
+func (r *Registry) RegisterSniffer(s Sniffer, p Processor, priority int) {
+	r.register(registration{
+		kind:      matchSniffer,
+		sniffer:   s,
+		processor: p,
+		priority:  priority,
+	})
+}
+
+func (r *Registry) register(reg registration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	reg.seq = r.seq
+	r.registrations = append(r.registrations, reg)
+}
+
+/*
+Lookup returns the processor to use for a piece of content given the content type reported for it,
+its source and its leading bytes. Any of these can be empty.
+*/
+func (r *Registry) Lookup(contentType, source string, head []byte) (Processor, error) {
+	reg, err := r.lookup(contentType, source, head)
+	if err != nil {
+		return nil, err
+	}
+	return reg.processor, nil
+}
+
+// lookup is Lookup returning the registration that matched, so the caller can tell what it matched.
+func (r *Registry) lookup(contentType, source string, head []byte) (registration, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	mt := mediaType(contentType)
+	ext, _ := utils.GetFileExtension(source)
+	ext = strings.ToLower(ext)
+
+	var best *registration
+	for i := range r.registrations {
+		reg := &r.registrations[i]
+
+		switch reg.kind {
+		case matchContentType:
+			if mt == "" || reg.key != mt {
+				continue
+			}
+		case matchExtension:
+			if ext == "" || reg.key != ext {
+				continue
+			}
+		case matchSniffer:
+			if len(head) == 0 || !reg.sniffer(head) {
+				continue
+			}
+		}
+
+		if best == nil || reg.beats(best) {
+			best = reg
+		}
+	}
+
+	if best == nil {
+		return registration{}, fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+	}
+
+	return *best, nil
+}
+
+/*
+matchedType returns the content type of content that reg was matched for, which is the content type
+it came with unless the processor was picked by its extension or by sniffing its leading bytes.
+*/
+func matchedType(reg registration, contentType string, head []byte) string {
+	switch reg.kind {
+	case matchExtension:
+		if t := extensionType(reg.key); t != "" {
+			return t
+		}
+	case matchSniffer:
+		if t := sniff.Detect(head); t != "" {
+			return t
+		}
+	}
+	return mediaType(contentType)
+}
+
+func (reg *registration) beats(other *registration) bool {
+	if reg.priority != other.priority {
+		return reg.priority > other.priority
+	}
+	if reg.kind != other.kind {
+		return reg.kind < other.kind
+	}
+	return reg.seq > other.seq
+}
+
+/*
+isText reports whether content is text, which is transcoded to UTF-8 before it's processed. Content
+without a usable content type is judged by its extension and its leading bytes.
+*/
+func isText(contentType, source string, head []byte) bool {
+	mt := mediaType(contentType)
+	if mt == "" || mt == "application/octet-stream" {
+		ext, _ := utils.GetFileExtension(source)
+		return isTextType(extensionType(ext)) || isTextType(sniff.Detect(head))
+	}
+	return isTextType(mt)
+}
+
+// extensionTypes are the content types of the extensions of the formats handled by the built-in processors.
+var extensionTypes = map[string]string{
+	".txt": contentTypeText, ".md": contentTypeMarkdown, ".csv": contentTypeCSV, ".json": contentTypeJSON,
+	".yaml": contentTypeYAML, ".yml": contentTypeYAML, ".html": contentTypeHTML, ".htm": contentTypeHTML,
+	".xml": contentTypeXML, ".pdf": contentTypePDF, ".docx": contentTypeDocx, ".pptx": contentTypePptx,
+	".epub": contentTypeEPUB, ".zip": contentTypeZip, ".tar": contentTypeTar, ".gz": contentTypeGzip,
+	".tgz": contentTypeGzip,
+}
+
+// extensionType returns the content type of files with the extension ext, if it's known.
+func extensionType(ext string) string {
+	ext = strings.ToLower(ext)
+	if t, ok := extensionTypes[ext]; ok {
+		return t
+	}
+	return mediaType(mime.TypeByExtension(ext))
+}
+
+func isTextType(contentType string) bool {
+	mt := mediaType(contentType)
+	switch {
+	case strings.HasPrefix(mt, "text/"), strings.HasSuffix(mt, "+xml"):
+		return true
+	case mt == contentTypeXML, mt == contentTypeJSON, mt == contentTypeYAML:
+		return true
+	}
+	return false
+}
+
+/*
+isGenericZip reports whether contentType says no more than that the content is a ZIP archive, which
+could still be any of the document formats based on ZIP.
+*/
+func isGenericZip(contentType string) bool {
+	switch mediaType(contentType) {
+	case "", "application/octet-stream", contentTypeZip, "application/x-zip-compressed":
+		return true
+	}
+	return false
+}
+
+// sniffs returns a Sniffer matching the content sniff.Detect recognizes as contentType.
+func sniffs(contentType string) Sniffer {
+	return func(head []byte) bool {
+		return sniff.Detect(head) == contentType
+	}
+}
+
+// mediaType strips any parameters from a content type and normalises its case.
+func mediaType(contentType string) string {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mt
+	}
+	mt, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(mt))
+}
+
+/*
+newDefaultRegistry returns a registry populated with the built-in processors.
+
+The extensions are meant as a fallback in case the content type is not recognized. i.e. if the
+server returns application/octet-stream but the file is a markdown file. When neither is of any
+help the content itself is sniffed, see the sniff package.
+
+The HTML and EPUB processors are configured by config.HTML.
+*/
+func newDefaultRegistry(config Config) *Registry {
+	r := NewRegistry()
+
+	processHTML := text.NewHTMLProcessor(config.HTML)
+	processEpub := document.NewEpubProcessor(config.HTML)
+
+	for contentType, proc := range map[string]ProcessorFunc{
+		contentTypeHTML:     processHTML,
+		contentTypeCSV:      text.ProcessCSV,
+		contentTypeJSON:     text.ProcessJSON,
+		contentTypeYAML:     text.ProcessYAML,
+		contentTypeMarkdown: text.ProcessMd,
+		contentTypeText:     text.ProcessText,
+		contentTypeXML:      text.ProcessXML,
+		contentTypeTextXML:  text.ProcessXML,
+		contentTypeDocx:     document.ProcessDocx,
+		contentTypePptx:     document.ProcessPptx,
+		contentTypePDF:      document.ProcessPDF,
+		contentTypeEPUB:     processEpub,
+	} {
+		r.RegisterContentType(contentType, proc, 0)
+	}
+
+	// sniffers are the last resort, for content without a usable content type or extension
+	for contentType, proc := range map[string]ProcessorFunc{
+		contentTypeHTML: processHTML,
+		contentTypeJSON: text.ProcessJSON,
+		contentTypeXML:  text.ProcessXML,
+		contentTypePDF:  document.ProcessPDF,
+		contentTypeDocx: document.ProcessDocx,
+		contentTypePptx: document.ProcessPptx,
+		contentTypeEPUB: processEpub,
+	} {
+		r.RegisterSniffer(sniffs(contentType), proc, 0)
+	}
+
+	for ext, proc := range map[string]ProcessorFunc{
+		".md":   text.ProcessMd,
+		".csv":  text.ProcessCSV,
+		".json": text.ProcessJSON,
+		".yaml": text.ProcessYAML,
+		".html": processHTML,
+		".epub": processEpub,
+	} {
+		r.RegisterExtension(ext, proc, 0)
+	}
+
+	return r
+}
@@ -0,0 +1,304 @@
+package chew
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+func namedProcessor(name string) ProcessorFunc {
+	return func(r io.Reader, url string) ([]common.Chunk, error) {
+		return []common.Chunk{{Content: name, Source: url}}, nil
+	}
+}
+
+func processorName(t *testing.T, p Processor) string {
+	t.Helper()
+	chunks, err := p.Process(strings.NewReader(""), "")
+	if err != nil || len(chunks) != 1 {
+		t.Fatalf("processor returned %v, %v", chunks, err)
+	}
+	return chunks[0].Content
+}
+
+func TestRegistry_Lookup(t *testing.T) {
+	isPDF := func(head []byte) bool { return bytes.HasPrefix(head, []byte("%PDF-")) }
+
+	registry := NewRegistry()
+	registry.RegisterContentType("text/html", namedProcessor("html"), 0)
+	registry.RegisterContentType("text/plain", namedProcessor("text"), 0)
+	registry.RegisterExtension(".md", namedProcessor("markdown"), 0)
+	registry.RegisterExtension("csv", namedProcessor("csv"), 0)
+	registry.RegisterSniffer(isPDF, namedProcessor("pdf"), 0)
+
+	type args struct {
+		contentType string
+		url         string
+		head        []byte
+	}
+	tests := []struct {
+		name    string
+		args    args
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "content type",
+			args: args{
+				contentType: "text/html",
+				url:         "https://example.com/page",
+			},
+			want:    "html",
+			wantErr: false,
+		},
+		{
+			name: "content type with parameters",
+			args: args{
+				contentType: "Text/HTML; charset=utf-8",
+				url:         "https://example.com/page",
+			},
+			want:    "html",
+			wantErr: false,
+		},
+		{
+			name: "content type beats extension",
+			args: args{
+				contentType: "text/plain",
+				url:         "https://example.com/README.md",
+			},
+			want:    "text",
+			wantErr: false,
+		},
+		{
+			name: "unknown content type falls back to extension",
+			args: args{
+				contentType: "application/octet-stream",
+				url:         "https://example.com/data.CSV",
+			},
+			want:    "csv",
+			wantErr: false,
+		},
+		{
+			name: "falls back to sniffer",
+			args: args{
+				contentType: "application/octet-stream",
+				url:         "https://example.com/download",
+				head:        []byte("%PDF-1.7"),
+			},
+			want:    "pdf",
+			wantErr: false,
+		},
+		{
+			name: "unsupported content type",
+			args: args{
+				contentType: "application/octet-stream",
+				url:         "https://example.com/page.htt",
+				head:        []byte("garbage"),
+			},
+			want:    "",
+			wantErr: true,
+		},
+		{
+			name: "no extension",
+			args: args{
+				contentType: "octet/stream",
+				url:         "https://example.com/page",
+			},
+			want:    "",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.Lookup(tt.args.contentType, tt.args.url, tt.args.head)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil && !errors.Is(err, ErrUnsupportedContentType) {
+				t.Errorf("Lookup() error = %v, want ErrUnsupportedContentType", err)
+			}
+			if got == nil {
+				return
+			}
+			if name := processorName(t, got); name != tt.want {
+				t.Errorf("Lookup() = %v, want %v", name, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistry_Priority(t *testing.T) {
+	isPDF := func(head []byte) bool { return bytes.HasPrefix(head, []byte("%PDF-")) }
+
+	tests := []struct {
+		name     string
+		register func(r *Registry)
+		want     string
+	}{
+		{
+			name: "later registration replaces earlier one",
+			register: func(r *Registry) {
+				r.RegisterContentType("application/pdf", namedProcessor("builtin"), 0)
+				r.RegisterContentType("application/pdf", namedProcessor("custom"), 0)
+			},
+			want: "custom",
+		},
+		{
+			name: "higher priority wins regardless of order",
+			register: func(r *Registry) {
+				r.RegisterContentType("application/pdf", namedProcessor("custom"), 1)
+				r.RegisterContentType("application/pdf", namedProcessor("builtin"), 0)
+			},
+			want: "custom",
+		},
+		{
+			name: "sniffer with higher priority beats content type",
+			register: func(r *Registry) {
+				r.RegisterContentType("application/pdf", namedProcessor("builtin"), 0)
+				r.RegisterSniffer(isPDF, namedProcessor("sniffed"), 10)
+			},
+			want: "sniffed",
+		},
+		{
+			name: "content type beats extension on equal priority",
+			register: func(r *Registry) {
+				r.RegisterExtension(".pdf", namedProcessor("extension"), 0)
+				r.RegisterContentType("application/pdf", namedProcessor("builtin"), 0)
+			},
+			want: "builtin",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.register(r)
+
+			got, err := r.Lookup("application/pdf", "https://example.com/file.pdf", []byte("%PDF-1.7"))
+			if err != nil {
+				t.Fatalf("Lookup() error = %v", err)
+			}
+			if name := processorName(t, got); name != tt.want {
+				t.Errorf("Lookup() = %v, want %v", name, tt.want)
+			}
+		})
+	}
+}
+
+func Test_newDefaultRegistry(t *testing.T) {
+	r := newDefaultRegistry(Config{})
+
+	tests := []struct {
+		name        string
+		contentType string
+		url         string
+		wantErr     bool
+	}{
+		{name: "html", contentType: "text/html; charset=utf-8", url: "https://example.com"},
+		{name: "pdf", contentType: "application/pdf", url: "https://example.com/file"},
+		{name: "markdown by extension", contentType: "application/octet-stream", url: "https://example.com/README.md"},
+		{name: "unsupported", contentType: "image/png", url: "https://example.com/logo.png", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Lookup(tt.contentType, tt.url, nil)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcess_Sniffing(t *testing.T) {
+	pdf, err := os.ReadFile("testdata/files/test.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	epub, err := os.ReadFile("testdata/files/test.epub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(pdf)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{"book": epub, "page": []byte("<!DOCTYPE html><p>sniffed</p>")} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		source string
+	}{
+		{name: "mislabelled response", source: server.URL + "/download"},
+		{name: "file without extension", source: "file://" + filepath.Join(dir, "book")},
+		{name: "html without extension", source: "file://" + filepath.Join(dir, "page")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 10})
+
+			chunks, err := c.Process(context.Background(), []string{tt.source})
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if len(chunks) == 0 {
+				t.Error("Process() returned no chunks")
+			}
+		})
+	}
+}
+
+func TestProcess_Charset(t *testing.T) {
+	pages := map[string]struct{ contentType, body string }{
+		"/page":       {contentType: "text/html", body: "<html><head><meta charset=\"shift_jis\"></head><body><p>\x93\xfa\x96\x7b\x8c\xea</p></body></html>"},
+		"/text":       {contentType: "text/plain; charset=windows-1252", body: "caf\xe9 cr\xe8me"},
+		"/euc-jp.xml": {contentType: "application/xml", body: "<?xml version=\"1.0\" encoding=\"EUC-JP\"?><title>\xc5\xec\xb5\xfe</title>"},
+		"/latin1.xml": {contentType: "text/xml", body: "<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><title>d\xe9j\xe0</title>"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := pages[r.URL.Path]
+		w.Header().Set("Content-Type", page.contentType)
+		w.Write([]byte(page.body))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/page", want: "日本語"},
+		{path: "/text", want: "café crème"},
+		{path: "/euc-jp.xml", want: "東京"},
+		{path: "/latin1.xml", want: "déjà"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			c := New(Config{IgnoreRobotsTxt: true, RateLimit: time.Millisecond, RateBurst: 10})
+
+			chunks, err := c.Process(context.Background(), []string{server.URL + tt.path})
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if len(chunks) != 1 || chunks[0].Content != tt.want {
+				t.Errorf("Process() = %+v, want a single chunk %q", chunks, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package chew
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"path"
+)
+
+/*
+ContentHint describes content handed to ProcessReader or ProcessBytes, since unlike a URL it doesn't
+say anything about itself. Every field is optional, content that can't be identified from the hint
+is sniffed.
+
+Fields:
+  - MIME: The content type of the content, parameters such as the charset are honored (e.g., "text/csv; charset=windows-1252")
+  - Filename: The name of the file the content came from, its extension is used when MIME doesn't help (e.g., "report.pdf")
+  - Source: What the chunks are attributed to in Chunk.Source, defaults to Filename (e.g., "s3://uploads/report.pdf")
+*/
+type ContentHint struct {
+	MIME     string
+	Filename string
+	Source   string
+}
+
+/*
+ProcessReader processes content that's already at hand, e.g. an upload or an object read from storage,
+through the same processor selection, chunking and metadata pipeline as Process. Nothing is fetched so
+robots.txt, rate limits and retries don't apply. Content that's only partly processed, e.g. an
+archive with an entry that failed, returns the chunks of the rest along with ErrPartialContent.
+
+Usage:
+
+	file, header, err := req.FormFile("document")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	chunks, err := c.ProcessReader(ctx, file, chew.ContentHint{
+	    MIME:     header.Header.Get("Content-Type"),
+	    Filename: header.Filename,
+	})
+*/
+func (c *Chew) ProcessReader(ctx context.Context, r io.Reader, hint ContentHint) ([]Chunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if hint.Source == "" {
+		hint.Source = hint.Filename
+	}
+	// the content type of the extension, as files opened by Process get, without a charset that may not hold
+	if mt := mediaType(hint.MIME); (mt == "" || mt == "application/octet-stream") && hint.Filename != "" {
+		if byExt := mediaType(mime.TypeByExtension(path.Ext(hint.Filename))); byExt != "" {
+			hint.MIME = byExt
+		}
+	}
+
+	chunks, err := c.processHinted(r, hint)
+	if err != nil {
+		return chunks, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return chunks, nil
+}
+
+/*
+ProcessBytes is ProcessReader for content held in memory.
+
+Usage:
+
+	chunks, err := c.ProcessBytes(ctx, blob, chew.ContentHint{Filename: "notes.md"})
+*/
+func (c *Chew) ProcessBytes(ctx context.Context, content []byte, hint ContentHint) ([]Chunk, error) {
+	return c.ProcessReader(ctx, bytes.NewReader(content), hint)
+}
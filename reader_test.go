@@ -0,0 +1,150 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestProcessBytes(t *testing.T) {
+	pdf, err := os.ReadFile("testdata/files/test.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		content     []byte
+		hint        ContentHint
+		wantType    string
+		wantSource  string
+		wantContent string
+		wantErr     error
+	}{
+		{
+			name:        "mime",
+			content:     []byte("<p>Hello</p>"),
+			hint:        ContentHint{MIME: "text/html"},
+			wantType:    "text/html",
+			wantContent: "Hello",
+		},
+		{
+			name:        "filename",
+			content:     []byte("name,age\nAda,36"),
+			hint:        ContentHint{Filename: "people.csv"},
+			wantSource:  "people.csv",
+			wantContent: "name",
+		},
+		{
+			name:        "source",
+			content:     []byte("caf\xe9"),
+			hint:        ContentHint{MIME: "text/plain; charset=windows-1252", Filename: "menu.txt", Source: "s3://bucket/menu"},
+			wantType:    "text/plain",
+			wantSource:  "s3://bucket/menu",
+			wantContent: "café",
+		},
+		{
+			name:        "text extension",
+			content:     []byte("plain notes"),
+			hint:        ContentHint{Filename: "notes.txt"},
+			wantType:    "text/plain",
+			wantSource:  "notes.txt",
+			wantContent: "plain notes",
+		},
+		{
+			name:        "xml extension",
+			content:     []byte("<items><item>value</item></items>"),
+			hint:        ContentHint{Filename: "data.xml"},
+			wantSource:  "data.xml",
+			wantContent: "value",
+		},
+		{
+			name:        "htm extension over octet-stream",
+			content:     []byte("<p>Hello</p>"),
+			hint:        ContentHint{MIME: "application/octet-stream", Filename: "page.htm"},
+			wantType:    "text/html",
+			wantSource:  "page.htm",
+			wantContent: "Hello",
+		},
+		{
+			name:        "yml extension",
+			content:     []byte("key: value"),
+			hint:        ContentHint{Filename: "conf.yml"},
+			wantSource:  "conf.yml",
+			wantContent: "value",
+		},
+		{
+			name:       "sniffed",
+			content:    pdf,
+			hint:       ContentHint{Filename: "upload"},
+			wantType:   "application/pdf",
+			wantSource: "upload#page=1",
+		},
+		{
+			name:        "content type the processor was picked for",
+			content:     []byte("<items><item>value</item></items>"),
+			hint:        ContentHint{MIME: "text/xml; charset=utf-8", Source: "feed"},
+			wantType:    "text/xml",
+			wantSource:  "feed",
+			wantContent: "value",
+		},
+		{
+			name:        "markdown extension",
+			content:     []byte("# Notes"),
+			hint:        ContentHint{Filename: "notes.md"},
+			wantType:    "text/markdown",
+			wantSource:  "notes.md",
+			wantContent: "Notes",
+		},
+		{
+			name:    "unsupported",
+			content: []byte{0x00, 0x01},
+			hint:    ContentHint{MIME: "application/octet-stream"},
+			wantErr: ErrUnsupportedContentType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{})
+
+			chunks, err := c.ProcessBytes(context.Background(), tt.content, tt.hint)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ProcessBytes() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProcessBytes() error = %v", err)
+			}
+			if len(chunks) == 0 {
+				t.Fatal("ProcessBytes() returned no chunks")
+			}
+
+			chunk := chunks[0]
+			if chunk.Source != tt.wantSource {
+				t.Errorf("Source = %q, want %q", chunk.Source, tt.wantSource)
+			}
+			if tt.wantType != "" && chunk.Metadata.ContentType != tt.wantType {
+				t.Errorf("ContentType = %q, want %q", chunk.Metadata.ContentType, tt.wantType)
+			}
+			if !strings.Contains(chunk.Content, tt.wantContent) {
+				t.Errorf("Content = %q, want it to contain %q", chunk.Content, tt.wantContent)
+			}
+			if chunk.Metadata.FetchedAt.IsZero() || chunk.Metadata.Tokens == 0 {
+				t.Errorf("Metadata = %+v, want FetchedAt and Tokens filled in", chunk.Metadata)
+			}
+		})
+	}
+}
+
+func TestProcessReader_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := New(Config{}).ProcessReader(ctx, strings.NewReader("text"), ContentHint{MIME: "text/plain"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ProcessReader() error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,197 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+// defaultMaxRetryDelay caps the backoff of the default retry policy.
+const defaultMaxRetryDelay = time.Minute
+
+/*
+RetryPolicy decides whether a failed attempt at fetching a source is retried. Retry is called with
+the number of attempts made so far, starting at 1, and the error of the last one, it returns how
+long to wait before the next attempt and whether there should be one at all.
+
+When Config.RetryPolicy isn't set a BackoffPolicy built from Config.RetryLimit and Config.RetryDelay
+is used.
+*/
+type RetryPolicy = common.RetryPolicy
+
+/*
+BackoffPolicy retries errors that IsRetryable reports as transient with an exponential backoff, the
+delay doubles with every attempt starting from BaseDelay. A Retry-After header sent along with a 429
+or 503 response is honored when it asks for a longer wait, unless it asks for longer than MaxDelay in
+which case the error is given up on rather than retried before the server is ready.
+
+Fields:
+  - MaxRetries: Number of retries after the first attempt, 0 means never retry (e.g., 3)
+  - BaseDelay: Delay before the first retry (e.g., time.Second)
+  - MaxDelay: Upper bound of the backoff, 0 means no bound (e.g., 30 * time.Second)
+  - Jitter: Fraction of the delay that's randomized so clients don't retry in lockstep, between 0 and 1 (e.g., 0.2)
+
+Usage:
+
+	c := chew.New(chew.Config{
+	    RetryPolicy: chew.BackoffPolicy{MaxRetries: 5, BaseDelay: time.Second, MaxDelay: time.Minute, Jitter: 0.2},
+	})
+*/
+type BackoffPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+	Jitter     float64
+}
+
+// Retry implements RetryPolicy.
+func (p BackoffPolicy) Retry(attempt int, err error) (time.Duration, bool) {
+	if attempt > p.MaxRetries || !IsRetryable(err) {
+		return 0, false
+	}
+
+	delay := p.BaseDelay
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 {
+		delay = min(delay, p.MaxDelay)
+	}
+
+	if jitter := min(max(p.Jitter, 0), 1); jitter > 0 && delay > 0 {
+		// spread the delay over [delay*(1-jitter), delay]
+		delay -= time.Duration(rand.Float64() * jitter * float64(delay))
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		if p.MaxDelay > 0 && httpErr.RetryAfter > p.MaxDelay {
+			return 0, false
+		}
+		delay = max(delay, httpErr.RetryAfter)
+	}
+
+	return delay, true
+}
+
+/*
+IsRetryable reports whether err is likely to be transient, that is a timeout, a connection that was
+refused, reset or closed before the response, a temporary DNS failure, a 408 or 429 response or a
+5xx response. Errors of the caller's context, cancelled or past its deadline, aren't retryable.
+
+Usage:
+
+	if err != nil && chew.IsRetryable(err) {
+	    queueForLater(url)
+	}
+*/
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || isContextDeadline(err) {
+		return false
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		switch {
+		case httpErr.StatusCode == http.StatusRequestTimeout, httpErr.StatusCode == http.StatusTooManyRequests:
+			return true
+		case httpErr.StatusCode >= 500 && httpErr.StatusCode <= 599:
+			return true
+		}
+		return false
+	}
+
+	// a request timing out (as opposed to the context) surfaces as a net.Error
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return isTransientNetworkError(err)
+}
+
+// isTransientNetworkError reports whether err is a failure of the connection that may well not happen again.
+func isTransientNetworkError(err error) bool {
+	for _, errno := range []error{syscall.ECONNRESET, syscall.ECONNREFUSED, syscall.ECONNABORTED, syscall.EPIPE} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTemporary
+	}
+
+	// the server closing the connection before answering, only counted for requests since processors reading truncated content fail the same way
+	var urlErr *url.Error
+	return errors.As(err, &urlErr) && (errors.Is(urlErr.Err, io.EOF) || errors.Is(urlErr.Err, io.ErrUnexpectedEOF))
+}
+
+/*
+isContextDeadline reports whether err wraps context.DeadlineExceeded, the error of a context whose
+deadline passed. It's a net.Error timing out, but so are the timeouts of http.Client, which match it
+with errors.Is without being the caller's context running out, so only the error itself counts.
+*/
+func isContextDeadline(err error) bool {
+	if err == context.DeadlineExceeded {
+		return true
+	}
+
+	switch u := err.(type) {
+	case interface{ Unwrap() error }:
+		return isContextDeadline(u.Unwrap())
+	case interface{ Unwrap() []error }:
+		for _, err := range u.Unwrap() {
+			if isContextDeadline(err) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/*
+parseRetryAfter parses the Retry-After header, which holds either a number of seconds or an HTTP
+date, and returns how long to wait from now. Values that can't be parsed or are in the past give 0.
+*/
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+
+	return 0
+}
+
+// retryPolicy returns the configured retry policy or the default built from RetryLimit and RetryDelay.
+func retryPolicy(config common.Config) RetryPolicy {
+	if config.RetryPolicy != nil {
+		return config.RetryPolicy
+	}
+
+	return BackoffPolicy{
+		MaxRetries: config.RetryLimit,
+		BaseDelay:  config.RetryDelay,
+		MaxDelay:   max(defaultMaxRetryDelay, config.RetryDelay),
+		Jitter:     0.2,
+	}
+}
@@ -0,0 +1,228 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestBackoffPolicy_Retry(t *testing.T) {
+	unavailable := &HTTPError{StatusCode: http.StatusServiceUnavailable}
+
+	tests := []struct {
+		name      string
+		policy    BackoffPolicy
+		attempt   int
+		err       error
+		wantDelay time.Duration
+		wantRetry bool
+	}{
+		{
+			name:      "first retry",
+			policy:    BackoffPolicy{MaxRetries: 3, BaseDelay: time.Second},
+			attempt:   1,
+			err:       unavailable,
+			wantDelay: time.Second,
+			wantRetry: true,
+		},
+		{
+			name:      "exponential",
+			policy:    BackoffPolicy{MaxRetries: 3, BaseDelay: time.Second},
+			attempt:   3,
+			err:       unavailable,
+			wantDelay: 4 * time.Second,
+			wantRetry: true,
+		},
+		{
+			name:      "capped",
+			policy:    BackoffPolicy{MaxRetries: 10, BaseDelay: time.Second, MaxDelay: 5 * time.Second},
+			attempt:   10,
+			err:       unavailable,
+			wantDelay: 5 * time.Second,
+			wantRetry: true,
+		},
+		{
+			name:      "retry after",
+			policy:    BackoffPolicy{MaxRetries: 3, BaseDelay: time.Second},
+			attempt:   1,
+			err:       fmt.Errorf("processing: %w", &HTTPError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Minute}),
+			wantDelay: time.Minute,
+			wantRetry: true,
+		},
+		{
+			name:      "retry after over the cap",
+			policy:    BackoffPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Minute},
+			attempt:   1,
+			err:       &HTTPError{StatusCode: http.StatusServiceUnavailable, RetryAfter: 24 * time.Hour},
+			wantRetry: false,
+		},
+		{
+			name:    "out of retries",
+			policy:  BackoffPolicy{MaxRetries: 3, BaseDelay: time.Second},
+			attempt: 4,
+			err:     unavailable,
+		},
+		{
+			name:    "not retryable",
+			policy:  BackoffPolicy{MaxRetries: 3, BaseDelay: time.Second},
+			attempt: 1,
+			err:     &HTTPError{StatusCode: http.StatusNotFound},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, retry := tt.policy.Retry(tt.attempt, tt.err)
+			if delay != tt.wantDelay || retry != tt.wantRetry {
+				t.Errorf("Retry() = (%v, %v), want (%v, %v)", delay, retry, tt.wantDelay, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestBackoffPolicy_Jitter(t *testing.T) {
+	policy := BackoffPolicy{MaxRetries: 1, BaseDelay: time.Second, Jitter: 0.5}
+	for range 100 {
+		delay, _ := policy.Retry(1, &HTTPError{StatusCode: http.StatusBadGateway})
+		if delay < 500*time.Millisecond || delay > time.Second {
+			t.Fatalf("Retry() delay = %v, want between 500ms and 1s", delay)
+		}
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "server error", err: &HTTPError{StatusCode: http.StatusInternalServerError}, want: true},
+		{name: "too many requests", err: &HTTPError{StatusCode: http.StatusTooManyRequests}, want: true},
+		{name: "not found", err: &HTTPError{StatusCode: http.StatusNotFound}, want: false},
+		{name: "timeout", err: fmt.Errorf("making request: %w", os.ErrDeadlineExceeded), want: true},
+		{name: "unsupported content type", err: ErrUnsupportedContentType, want: false},
+		{name: "missing file", err: fmt.Errorf("opening file: %w", os.ErrNotExist), want: false},
+		{name: "cancelled", err: context.Canceled, want: false},
+		{name: "context deadline", err: &url.Error{Op: "Get", URL: "https://example.com", Err: context.DeadlineExceeded}, want: false},
+		{name: "client timeout", err: clientTimeout(t), want: true},
+		{name: "connection reset", err: requestError(&net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), want: true},
+		{name: "connection refused", err: requestError(&net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), want: true},
+		{name: "closed before the response", err: requestError(io.EOF), want: true},
+		{name: "temporary dns failure", err: requestError(&net.DNSError{Err: "server misbehaving", Name: "example.com", IsTemporary: true}), want: true},
+		{name: "unknown host", err: requestError(&net.DNSError{Err: "no such host", Name: "example.com", IsNotFound: true}), want: false},
+		{name: "truncated content", err: fmt.Errorf("processing: %w", io.ErrUnexpectedEOF), want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+// requestError returns err as it's returned by http.Client, wrapped the way processURL wraps it.
+func requestError(err error) error {
+	return fmt.Errorf("making request: %w", &url.Error{Op: "Get", URL: "https://example.com", Err: err})
+}
+
+// clientTimeout returns the error of a request running into http.Client.Timeout.
+func clientTimeout(t *testing.T) error {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 10 * time.Millisecond}
+	resp, err := client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+		t.Fatal("request didn't time out")
+	}
+	return err
+}
+
+func Test_parseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "120", want: 2 * time.Minute},
+		{value: "Mon, 01 Jan 2024 12:00:30 GMT", want: 30 * time.Second},
+		{value: "Mon, 01 Jan 2024 11:00:00 GMT", want: 0},
+		{value: "-5", want: 0},
+		{value: "soon", want: 0},
+		{value: "", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value, now); got != tt.want {
+				t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcess_Retries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int
+		status       int
+		retryLimit   int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "recovers", failures: 2, status: http.StatusBadGateway, retryLimit: 3, wantAttempts: 3},
+		{name: "gives up", failures: 10, status: http.StatusInternalServerError, retryLimit: 2, wantAttempts: 3, wantErr: true},
+		{name: "no retries", failures: 10, status: http.StatusBadGateway, retryLimit: 0, wantAttempts: 1, wantErr: true},
+		{name: "not retryable", failures: 10, status: http.StatusNotFound, retryLimit: 3, wantAttempts: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if int(requests.Add(1)) <= tt.failures {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Header().Set("Content-Type", "text/plain")
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			c := New(Config{IgnoreRobotsTxt: true, RetryLimit: tt.retryLimit, RetryDelay: time.Millisecond, RateLimit: time.Millisecond, RateBurst: 10})
+
+			result, err := c.ProcessBatch(context.Background(), []string{server.URL})
+			if err != nil {
+				t.Fatalf("ProcessBatch() error = %v", err)
+			}
+			report := result.Reports[server.URL]
+			if (report.Err != nil) != tt.wantErr {
+				t.Errorf("ProcessBatch() report error = %v, wantErr %v", report.Err, tt.wantErr)
+			}
+			if report.Attempts != tt.wantAttempts || int(requests.Load()) != tt.wantAttempts {
+				t.Errorf("ProcessBatch() made %d attempts (%d requests), want %d", report.Attempts, requests.Load(), tt.wantAttempts)
+			}
+
+			var httpErr *HTTPError
+			if tt.wantErr && (!errors.As(report.Err, &httpErr) || httpErr.StatusCode != tt.status) {
+				t.Errorf("ProcessBatch() report error = %v, want status %d", report.Err, tt.status)
+			}
+		})
+	}
+}
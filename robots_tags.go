@@ -0,0 +1,104 @@
+package chew
+
+import (
+	"strings"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+)
+
+/*
+RobotsTagPolicy selects what happens to content marked noindex by an X-Robots-Tag header or a
+robots meta tag, see Config.RobotsTags. With any policy other than RobotsTagsIgnore Crawl also
+respects nofollow, both for whole pages and for links marked rel="nofollow".
+*/
+type RobotsTagPolicy = common.RobotsTagPolicy
+
+const (
+	// RobotsTagsIgnore processes content regardless of its robots tags.
+	RobotsTagsIgnore = common.RobotsTagsIgnore
+	// RobotsTagsSkip fails content marked noindex with ErrNoIndex.
+	RobotsTagsSkip = common.RobotsTagsSkip
+	// RobotsTagsFlag processes content marked noindex but sets Metadata.NoIndex on its chunks.
+	RobotsTagsFlag = common.RobotsTagsFlag
+)
+
+// robotsDirectives are the directives of the robots tags that apply to our user agent.
+type robotsDirectives struct {
+	noIndex  bool
+	noFollow bool
+}
+
+func (d *robotsDirectives) apply(directive string) {
+	switch strings.ToLower(strings.TrimSpace(directive)) {
+	case "noindex":
+		d.noIndex = true
+	case "nofollow":
+		d.noFollow = true
+	case "none":
+		d.noIndex = true
+		d.noFollow = true
+	}
+}
+
+/*
+agentToken returns the name robots tags address a user agent by, i.e. "chewbot" for
+"ChewBot/1.0 (+https://example.com/bot)".
+*/
+func agentToken(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), "/")
+	if fields := strings.Fields(token); len(fields) > 0 {
+		return strings.ToLower(fields[0])
+	}
+	return ""
+}
+
+/*
+parseXRobotsTag parses the values of the X-Robots-Tag header. A value applies to every crawler
+unless it starts with the name of one, i.e. "googlebot: noindex", in which case it only applies
+if that's the name of our user agent.
+*/
+func parseXRobotsTag(values []string, userAgent string) robotsDirectives {
+	var (
+		d     robotsDirectives
+		agent = agentToken(userAgent)
+	)
+
+	for _, value := range values {
+		applies := true
+		for _, directive := range strings.Split(value, ",") {
+			if name, rest, found := strings.Cut(directive, ":"); found && !isRuleWithValue(name) {
+				name = strings.ToLower(strings.TrimSpace(name))
+				applies = name == agent || name == "robots" || name == "*"
+				directive = rest
+			}
+			if applies {
+				d.apply(directive)
+			}
+		}
+	}
+
+	return d
+}
+
+// isRuleWithValue reports whether name is a directive that takes a value, as opposed to the name of a crawler.
+func isRuleWithValue(name string) bool {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "unavailable_after", "max-snippet", "max-image-preview", "max-video-preview":
+		return true
+	}
+	return false
+}
+
+// parseMetaRobots parses the robots meta tags of a page, both <meta name="robots"> and the one named after our user agent.
+func parseMetaRobots(meta map[string]string, userAgent string) robotsDirectives {
+	var d robotsDirectives
+	for _, name := range []string{"robots", agentToken(userAgent)} {
+		if name == "" {
+			continue
+		}
+		for _, directive := range strings.Split(meta[name], ",") {
+			d.apply(directive)
+		}
+	}
+	return d
+}
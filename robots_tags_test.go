@@ -0,0 +1,208 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_parseXRobotsTag(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []string
+		want   robotsDirectives
+	}{
+		{
+			name:   "all crawlers",
+			values: []string{"noindex, nofollow"},
+			want:   robotsDirectives{noIndex: true, noFollow: true},
+		},
+		{
+			name:   "none",
+			values: []string{"NONE"},
+			want:   robotsDirectives{noIndex: true, noFollow: true},
+		},
+		{
+			name:   "other crawler",
+			values: []string{"googlebot: noindex", "nofollow"},
+			want:   robotsDirectives{noFollow: true},
+		},
+		{
+			name:   "our crawler",
+			values: []string{"chewbot: noindex, nofollow"},
+			want:   robotsDirectives{noIndex: true, noFollow: true},
+		},
+		{
+			name:   "directive with a value",
+			values: []string{"unavailable_after: 25 Jun 2010 15:00:00 PST, noindex"},
+			want:   robotsDirectives{noIndex: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseXRobotsTag(tt.values, "ChewBot/1.0 (+https://example.com/bot)"); got != tt.want {
+				t.Errorf("parseXRobotsTag() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func newRobotsTagsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	pages := map[string]struct{ header, body string }{
+		"/":            {body: `<a href="/header">H</a> <a href="/meta">M</a> <a href="/hidden" rel="nofollow">X</a>`},
+		"/header":      {header: "noindex", body: `<p>Header</p> <a href="/from-header">F</a>`},
+		"/meta":        {body: `<meta name="robots" content="noindex, nofollow"><p>Meta</p> <a href="/from-meta">F</a>`},
+		"/hidden":      {body: `<p>Hidden</p>`},
+		"/from-header": {body: `<p>From header</p>`},
+		"/from-meta":   {body: `<p>From meta</p>`},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if page.header != "" {
+			w.Header().Set("X-Robots-Tag", page.header)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprintf(w, "<html><head>%s</head></html>", page.body)
+	}))
+}
+
+func TestProcess_RobotsTags(t *testing.T) {
+	server := newRobotsTagsServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		policy      RobotsTagPolicy
+		path        string
+		wantErr     bool
+		wantNoIndex bool
+	}{
+		{name: "ignore", policy: RobotsTagsIgnore, path: "/meta"},
+		{name: "skip header", policy: RobotsTagsSkip, path: "/header", wantErr: true},
+		{name: "skip meta", policy: RobotsTagsSkip, path: "/meta", wantErr: true},
+		{name: "flag meta", policy: RobotsTagsFlag, path: "/meta", wantNoIndex: true},
+		{name: "indexable", policy: RobotsTagsSkip, path: "/hidden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{IgnoreRobotsTxt: true, RobotsTags: tt.policy, RetryLimit: 2, RateLimit: time.Millisecond, RateBurst: 10})
+
+			var info fetchInfo
+			chunks, err := c.processSource(context.Background(), server.URL+tt.path, &info)
+			if tt.wantErr {
+				if !errors.Is(err, ErrNoIndex) {
+					t.Fatalf("processSource() error = %v, want ErrNoIndex", err)
+				}
+				if info.attempts != 1 {
+					t.Errorf("processSource() made %d attempts, want 1", info.attempts)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("processSource() error = %v", err)
+			}
+			if len(chunks) == 0 {
+				t.Fatal("processSource() returned no chunks")
+			}
+			for _, chunk := range chunks {
+				if chunk.Metadata.NoIndex != tt.wantNoIndex {
+					t.Errorf("chunk %q NoIndex = %v, want %v", chunk.Content, chunk.Metadata.NoIndex, tt.wantNoIndex)
+				}
+			}
+		})
+	}
+}
+
+func TestProcess_RobotsTagsBareTransport(t *testing.T) {
+	cache, err := NewDiskCache(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{name: "robots tags", config: Config{RobotsTags: RobotsTagsFlag}},
+		{name: "cache", config: Config{Cache: cache}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.IgnoreRobotsTxt = true
+			c := New(tt.config)
+			// a response without Request, as a RoundTripper other than http.Transport may return
+			c.SetHTTPClient(&http.Client{Transport: &mockTransport{response: &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"text/html"}},
+				Body:       io.NopCloser(strings.NewReader(`<html><head><meta name="robots" content="noindex"></head><body><p>Hidden</p></body></html>`)),
+			}}})
+
+			chunks, err := c.Process(context.Background(), []string{"https://example.com/page"})
+			if err != nil {
+				t.Fatalf("Process() error = %v", err)
+			}
+			if len(chunks) == 0 {
+				t.Fatal("Process() returned no chunks")
+			}
+			if wantNoIndex := tt.config.RobotsTags == RobotsTagsFlag; chunks[0].Metadata.NoIndex != wantNoIndex {
+				t.Errorf("NoIndex = %v, want %v", chunks[0].Metadata.NoIndex, wantNoIndex)
+			}
+		})
+	}
+}
+
+func TestCrawl_RobotsTags(t *testing.T) {
+	server := newRobotsTagsServer(t)
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		policy RobotsTagPolicy
+		want   []string
+	}{
+		{
+			name:   "ignore",
+			policy: RobotsTagsIgnore,
+			want:   []string{"/", "/from-header", "/from-meta", "/header", "/hidden", "/meta"},
+		},
+		{
+			name:   "skip",
+			policy: RobotsTagsSkip,
+			want:   []string{"/", "/from-header"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(Config{IgnoreRobotsTxt: true, RobotsTags: tt.policy, RateLimit: time.Millisecond, RateBurst: 10})
+
+			result, err := c.Crawl(context.Background(), []string{server.URL + "/"}, CrawlOptions{})
+			if err != nil {
+				t.Fatalf("Crawl() error = %v", err)
+			}
+
+			var got []string
+			for url := range result.Chunks {
+				got = append(got, url[len(server.URL):])
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Crawl() indexed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package chew
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/mmatongo/chew/v1/internal/sitemap"
+)
+
+// maxSitemapDepth is how deep sitemap indexes are followed, the protocol only allows one level.
+const maxSitemapDepth = 3
+
+/*
+SitemapOptions controls which URLs are taken from sitemaps.
+
+Fields:
+  - Since: Only URLs modified at or after this time are kept, URLs without a lastmod are always kept (e.g., time.Now().AddDate(0, 0, -7))
+  - MaxURLs: Maximum number of URLs returned, 0 means no limit (e.g., 1000)
+*/
+type SitemapOptions struct {
+	Since   time.Time
+	MaxURLs int
+}
+
+/*
+Sitemaps returns the sitemaps of a site as declared by the Sitemap lines of its robots.txt, if it
+doesn't declare any the conventional /sitemap.xml is returned.
+
+Usage:
+
+	sitemaps, err := c.Sitemaps(ctx, "https://example.com")
+*/
+func (c *Chew) Sitemaps(ctx context.Context, site string) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	parsedURL, err := url.Parse(site)
+	if err != nil {
+		return nil, fmt.Errorf("parsing site URL: %w", err)
+	}
+	if parsedURL.Host == "" {
+		return nil, fmt.Errorf("site URL %s has no host", site)
+	}
+
+	if robotsData := c.getRobotsData(ctx, parsedURL); robotsData != nil && len(robotsData.Sitemaps) > 0 {
+		return robotsData.Sitemaps, nil
+	}
+
+	return []string{fmt.Sprintf("%s://%s/sitemap.xml", parsedURL.Scheme, parsedURL.Host)}, nil
+}
+
+/*
+SitemapURLs fetches the sitemaps, following sitemap indexes, and returns the URLs they list that pass
+opts, each once. Gzipped sitemaps are supported. Fetching sitemaps goes through the rate limiter, the
+robots.txt and crawl delay checks, the configured http.Client and User-Agent.
+
+A sitemap that can't be fetched or parsed doesn't stop the others, the URLs found are returned along
+with an error joining every failure.
+
+Usage:
+
+	urls, err := c.SitemapURLs(ctx, []string{"https://example.com/sitemap.xml"}, chew.SitemapOptions{
+	    Since: lastRun,
+	})
+*/
+func (c *Chew) SitemapURLs(ctx context.Context, sitemaps []string, opts SitemapOptions) ([]string, error) {
+	var (
+		urls    []string
+		errs    []error
+		seen    = make(map[string]bool)
+		visited = make(map[string]bool)
+	)
+
+	var walk func(loc string, depth int) bool
+	walk = func(loc string, depth int) bool {
+		if visited[loc] {
+			return true
+		}
+		visited[loc] = true
+
+		sm, err := c.fetchSitemap(ctx, loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("sitemap %s: %w", loc, err))
+			return ctx.Err() == nil
+		}
+
+		for _, u := range sm.URLs {
+			if !u.Modified(opts.Since) || seen[u.Loc] {
+				continue
+			}
+			seen[u.Loc] = true
+			urls = append(urls, u.Loc)
+			if opts.MaxURLs > 0 && len(urls) >= opts.MaxURLs {
+				return false
+			}
+		}
+
+		if depth >= maxSitemapDepth {
+			return true
+		}
+		for _, child := range sm.Sitemaps {
+			// a sitemap that wasn't modified since can't list anything that was
+			if !child.Modified(opts.Since) {
+				continue
+			}
+			if !walk(child.Loc, depth+1) {
+				return false
+			}
+		}
+		return true
+	}
+
+	for _, loc := range sitemaps {
+		if !walk(loc, 0) {
+			break
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return urls, err
+	}
+
+	return urls, errors.Join(errs...)
+}
+
+/*
+ProcessSitemap finds the sitemaps of a site, collects the URLs they list and processes them with
+ProcessBatch, so every URL goes through the usual robots.txt, crawl delay and rate limiting checks.
+If some of the sitemaps couldn't be fetched the error is reported in the result under the site URL.
+
+Usage:
+
+	result, err := c.ProcessSitemap(ctx, "https://example.com", chew.SitemapOptions{Since: lastRun})
+*/
+func (c *Chew) ProcessSitemap(ctx context.Context, site string, opts SitemapOptions) (*BatchResult, error) {
+	sitemaps, err := c.Sitemaps(ctx, site)
+	if err != nil {
+		return nil, err
+	}
+
+	urls, err := c.SitemapURLs(ctx, sitemaps, opts)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+
+	result, batchErr := c.ProcessBatch(ctx, urls)
+	if err != nil {
+		result.Reports[site] = &SourceReport{Err: err}
+	}
+
+	return result, batchErr
+}
+
+// fetchSitemap fetches and parses a sitemap, it's held to robots.txt and the crawl delay like any other fetch.
+func (c *Chew) fetchSitemap(ctx context.Context, loc string) (*sitemap.Sitemap, error) {
+	if err := c.politeWait(ctx, loc); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, loc, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+
+	if err := c.hostLimiters.wait(ctx, req.URL.Host); err != nil {
+		return nil, rateLimitError(ctx, err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	c.hostLimiters.observe(req.URL.Host, resp.StatusCode)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newHTTPError(resp, loc)
+	}
+
+	return sitemap.Parse(resp.Body)
+}
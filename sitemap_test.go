@@ -0,0 +1,169 @@
+package chew
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newSitemapServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprintf(w, "User-agent: *\nAllow: /\n\nSitemap: %s/sitemap_index.xml\n", server.URL)
+		case "/sitemap_index.xml":
+			fmt.Fprintf(w, `<sitemapindex>
+				<sitemap><loc>%[1]s/sitemap-new.xml.gz</loc><lastmod>2024-06-01</lastmod></sitemap>
+				<sitemap><loc>%[1]s/sitemap-old.xml</loc><lastmod>2023-01-01</lastmod></sitemap>
+				<sitemap><loc>%[1]s/sitemap-missing.xml</loc></sitemap>
+			</sitemapindex>`, server.URL)
+		case "/sitemap-new.xml.gz":
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			fmt.Fprintf(gz, `<urlset>
+				<url><loc>%[1]s/a</loc><lastmod>2024-06-01</lastmod></url>
+				<url><loc>%[1]s/b</loc><lastmod>2023-06-01</lastmod></url>
+				<url><loc>%[1]s/c</loc></url>
+			</urlset>`, server.URL)
+			gz.Close()
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write(buf.Bytes())
+		case "/sitemap-old.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%s/old</loc></url></urlset>`, server.URL)
+		case "/a", "/c", "/old":
+			w.Header().Set("Content-Type", "text/plain")
+			fmt.Fprintf(w, "page %s", r.URL.Path)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	return server
+}
+
+func TestSitemaps(t *testing.T) {
+	server := newSitemapServer(t)
+	defer server.Close()
+
+	c := New(Config{RateLimit: time.Millisecond, RateBurst: 10})
+
+	got, err := c.Sitemaps(context.Background(), server.URL+"/some/page")
+	if err != nil {
+		t.Fatalf("Sitemaps() error = %v", err)
+	}
+	if want := []string{server.URL + "/sitemap_index.xml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sitemaps() = %v, want %v", got, want)
+	}
+
+	empty := httptest.NewServer(http.NotFoundHandler())
+	defer empty.Close()
+
+	got, err = c.Sitemaps(context.Background(), empty.URL)
+	if err != nil {
+		t.Fatalf("Sitemaps() error = %v", err)
+	}
+	if want := []string{empty.URL + "/sitemap.xml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sitemaps() = %v, want %v", got, want)
+	}
+}
+
+func TestSitemapURLs(t *testing.T) {
+	server := newSitemapServer(t)
+	defer server.Close()
+
+	c := New(Config{RateLimit: time.Millisecond, RateBurst: 10})
+	sitemaps := []string{server.URL + "/sitemap_index.xml"}
+
+	tests := []struct {
+		name string
+		opts SitemapOptions
+		want []string
+	}{
+		{name: "everything", opts: SitemapOptions{}, want: []string{"/a", "/b", "/c", "/old"}},
+		{name: "since", opts: SitemapOptions{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}, want: []string{"/a", "/c"}},
+		{name: "max urls", opts: SitemapOptions{MaxURLs: 2}, want: []string{"/a", "/b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			urls, err := c.SitemapURLs(context.Background(), sitemaps, tt.opts)
+			if tt.opts.MaxURLs == 0 && err == nil {
+				t.Errorf("SitemapURLs() error = nil, want the missing sitemap reported")
+			}
+
+			var got []string
+			for _, u := range urls {
+				got = append(got, u[len(server.URL):])
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SitemapURLs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessSitemap(t *testing.T) {
+	server := newSitemapServer(t)
+	defer server.Close()
+
+	c := New(Config{RateLimit: time.Millisecond, RateBurst: 10})
+
+	result, err := c.ProcessSitemap(context.Background(), server.URL, SitemapOptions{Since: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)})
+	if err != nil {
+		t.Fatalf("ProcessSitemap() error = %v", err)
+	}
+
+	var got []string
+	for url := range result.Chunks {
+		got = append(got, url[len(server.URL):])
+	}
+	sort.Strings(got)
+	if want := []string{"/a", "/c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ProcessSitemap() processed %v, want %v", got, want)
+	}
+
+	if report := result.Reports[server.URL]; report == nil || report.Err == nil {
+		t.Errorf("ProcessSitemap() report for the site = %+v, want the missing sitemap", report)
+	}
+}
+
+func TestSitemapURLs_RobotsTxt(t *testing.T) {
+	var fetched atomic.Bool
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		case "/sitemap.xml":
+			fmt.Fprintf(w, `<urlset><url><loc>%s/a</loc></url></urlset>`, server.URL)
+		case "/private/sitemap.xml":
+			fetched.Store(true)
+			fmt.Fprintf(w, `<urlset><url><loc>%s/private/a</loc></url></urlset>`, server.URL)
+		}
+	}))
+	defer server.Close()
+
+	c := New(Config{RateLimit: time.Millisecond, RateBurst: 10})
+
+	urls, err := c.SitemapURLs(context.Background(), []string{server.URL + "/sitemap.xml", server.URL + "/private/sitemap.xml"}, SitemapOptions{})
+	if !errors.Is(err, ErrDisallowedByRobots) {
+		t.Errorf("SitemapURLs() error = %v, want %v", err, ErrDisallowedByRobots)
+	}
+	if fetched.Load() {
+		t.Error("SitemapURLs() fetched a sitemap disallowed by robots.txt")
+	}
+	if want := []string{server.URL + "/a"}; !reflect.DeepEqual(urls, want) {
+		t.Errorf("SitemapURLs() = %v, want %v", urls, want)
+	}
+}
@@ -0,0 +1,50 @@
+package chew
+
+import (
+	"io"
+
+	"github.com/mmatongo/chew/v1/internal/common"
+	"github.com/mmatongo/chew/v1/internal/tokenizer"
+)
+
+/*
+Tokenizer counts the tokens in a piece of text. It's used to fill in Metadata.Tokens and
+to enforce ChunkingOptions.MaxTokens.
+
+When no tokenizer is configured chew estimates the count, which is good enough for a rough
+idea of the size of a chunk but not for staying within the hard limits of embedding models.
+For that, load the vocabulary of the model with LoadTokenizer.
+*/
+type Tokenizer = common.Tokenizer
+
+/*
+BPETokenizer is an offline byte level BPE tokenizer compatible with tiktoken encodings such as
+cl100k_base, which is used by OpenAI's embedding models.
+*/
+type BPETokenizer = tokenizer.BPE
+
+/*
+LoadTokenizer loads a BPE vocabulary in the tiktoken format from a file. The vocabularies aren't
+bundled with chew, cl100k_base can be downloaded from
+https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken
+
+Usage:
+
+	tok, err := chew.LoadTokenizer("cl100k_base.tiktoken")
+	if err != nil {
+		log.Fatalf("Error loading tokenizer: %v", err)
+	}
+
+	c := chew.New(chew.Config{
+		Tokenizer: tok,
+		Chunking:  chew.ChunkingOptions{Strategy: chew.ChunkRecursive, MaxTokens: 512},
+	})
+*/
+func LoadTokenizer(path string) (*BPETokenizer, error) {
+	return tokenizer.LoadFile(path)
+}
+
+// NewTokenizer reads a BPE vocabulary in the tiktoken format, see LoadTokenizer.
+func NewTokenizer(r io.Reader) (*BPETokenizer, error) {
+	return tokenizer.Load(r)
+}
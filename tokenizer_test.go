@@ -0,0 +1,51 @@
+package chew
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadTokenizer(t *testing.T) {
+	var vocab strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&vocab, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	fmt.Fprintf(&vocab, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(" chunk")), 256)
+
+	path := filepath.Join(t.TempDir(), "test.tiktoken")
+	if err := os.WriteFile(path, []byte(vocab.String()), 0644); err != nil {
+		t.Fatalf("failed to write vocabulary: %v", err)
+	}
+
+	tok, err := LoadTokenizer(path)
+	if err != nil {
+		t.Fatalf("LoadTokenizer() error = %v", err)
+	}
+
+	chew := New(Config{
+		Tokenizer: tok,
+		Chunking:  ChunkingOptions{Strategy: ChunkByParagraph, MaxTokens: 8},
+	})
+
+	got, err := chew.processContent(strings.NewReader("a chunk\n\nb chunk\n\nc chunk"), "text/plain", "file://test.txt")
+	if err != nil {
+		t.Fatalf("processContent() error = %v", err)
+	}
+
+	if len(got) != 2 {
+		t.Fatalf("processContent() returned %d chunks, want 2", len(got))
+	}
+	for _, chunk := range got {
+		if chunk.Metadata.Tokens != tok.Count(chunk.Content) || chunk.Metadata.Tokens > 8 {
+			t.Errorf("chunk %q has %d tokens", chunk.Content, chunk.Metadata.Tokens)
+		}
+	}
+
+	if _, err := LoadTokenizer(filepath.Join(t.TempDir(), "missing.tiktoken")); err == nil {
+		t.Error("LoadTokenizer() error = nil, want an error")
+	}
+}